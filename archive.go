@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// archiveSettings control which completed tasks get archived, and how.
+// Archiving only moves tasks out of the way of project filters. They're still
+// exported, so it doesn't make sessions on big databases any faster.
+type archiveSettings struct {
+	// Project moves archived tasks under it, keeping their own project as
+	// a subproject. Archiving needs one.
+	Project string `json:"project,omitempty"`
+	// After is how long ago a task must have been completed.
+	After duration `json:"after,omitempty"`
+	// Auto archives at the start of each session, without asking.
+	Auto bool `json:"auto,omitempty"`
+}
+
+// archivable returns the completed and reviewed tasks, which have been done
+// for long enough and aren't archived yet.
+func archivable(tasks []task) []task {
+	now := time.Now().UTC()
+	var res []task
+	for _, tk := range tasks {
+		end, ok := tk.ended()
+		if !ok || tk.Status != "completed" || !tk.isReviewed() ||
+			now.Sub(end) < time.Duration(cfg.Archive.After) {
+			continue
+		}
+		if p := cfg.Archive.Project; tk.Project == p || strings.HasPrefix(tk.Project, p+".") {
+			continue
+		}
+		res = append(res, tk)
+	}
+	return res
+}
+
+func (tk *task) archive() {
+	p := cfg.Archive.Project
+	if len(tk.Project) > 0 {
+		p += "." + tk.Project
+	}
+	tk.Project = p
+}
+
+// archiveTasks archives the tasks matching the filter, once confirmed. With
+// auto set, it doesn't ask.
+func archiveTasks(filter string, auto bool) {
+	if len(cfg.Archive.Project) == 0 {
+		if !auto {
+			fmt.Println("\nSet archive.project in the settings to archive. Press any key to continue.")
+			readKey()
+		}
+		return
+	}
+	tasks, err := getTasks(withWindow(filter, "all"))
+	if err != nil {
+		lg.Fatalf("While getting completed tasks for filter %q: %v", filter, err)
+	}
+	old := archivable(tasks)
+	if len(old) == 0 {
+		if !auto {
+			fmt.Println("\nNothing to archive. Press any key to continue.")
+			readKey()
+		}
+		return
+	}
+	if !auto {
+		fmt.Printf("\nArchive %d reviewed and completed tasks under project %s? They stay in taskwarrior. [y/N] ",
+			len(old), cfg.Archive.Project)
+		if r := readKey(); r != 'y' && r != 'Y' {
+			fmt.Println()
+			return
+		}
+		fmt.Println()
+	}
+	for i := range old {
+		old[i].archive()
+	}
+	if err := importBatch(old); err == nil {
+		lg.Infof("Archived %d tasks.", len(old))
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var weighByColor = flag.Bool("weigh", false,
+	"Weigh pending tasks by color, when suggesting the least loaded assignee.")
+
+// suggestAssignee returns the assignee with the least pending load, or an
+// empty string if there are no assignees.
+func suggestAssignee() string {
+	tasks, err := getTasks("")
+	if err != nil {
+		lg.Fatalf("While getting all tasks: %v", err)
+	}
+	load := make(map[string]int)
+	for _, tk := range tasks {
+		u := tk.userTag()
+		if len(u) == 0 {
+			continue
+		}
+		w := 1
+		if *weighByColor {
+			if i, ok := findState(tk.colorTag()); ok && cfg.States[i].Weight > 0 {
+				w = cfg.States[i].Weight
+			}
+		}
+		load[u] += w
+	}
+
+	var best string
+	for _, u := range assignees(tasks) {
+		if len(best) == 0 || load[u] < load[best] {
+			best = u
+		}
+	}
+	lg.Debugf("Assignee load: %v. Suggesting: %q", load, best)
+	return best
+}
+
+// pendingFilter strips the completed window out of the filter, so only
+// pending tasks match.
+func pendingFilter(filter string) string {
+	return withWindow(filter, "")
+}
+
+// assignees returns the sorted, unique user tags across tasks.
+func assignees(tasks []task) []string {
+	seen := make(map[string]bool)
+	var users []string
+	for _, tk := range tasks {
+		u := tk.userTag()
+		if len(u) == 0 || seen[u] {
+			continue
+		}
+		seen[u] = true
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	return users
+}
+
+func printAssigneeSummary(user string, tasks []task) {
+	var colors = make(map[string]int)
+	var disputed, reviewed, pomodoros int
+	for _, tk := range tasks {
+		pomodoros += tk.Pomodoros
+		colors[tk.colorTag()]++
+		if tk.isDisputed() {
+			disputed++
+		}
+		if tk.isReviewed() {
+			reviewed++
+		}
+	}
+	fmt.Println()
+	color.New(color.BgYellow, color.FgBlack).Printf(" %s ", user)
+	fmt.Printf(" %d pending:", len(tasks))
+	for _, s := range cfg.States {
+		fmt.Print(" ")
+		stateColor(s.Name).Printf(" %d %s ", colors[s.Name], stateBadge(s.Name))
+	}
+	fmt.Printf(", %d disputed, %d reviewed, %d pomodoros.\n", disputed, reviewed, pomodoros)
+}
+
+// reviewByAssignee walks through the pending tasks one assignee at a time,
+// the way a round of 1:1s would. If user is empty, it cycles through
+// every assignee matching the filter.
+func reviewByAssignee(filter, user string) {
+	filter = pendingFilter(filter)
+	var users []string
+	if len(user) > 0 {
+		users = []string{user}
+	} else {
+		all, err := getTasks(filter)
+		if err != nil {
+			lg.Fatalf("While getting tasks for filter %q: %v", filter, err)
+		}
+		users = assignees(all)
+	}
+
+	for i, u := range users {
+		tasks, err := getTasks(strings.TrimSpace(filter + " +" + u))
+		if err != nil {
+			lg.Fatalf("While getting tasks for %v: %v", u, err)
+		}
+		clear()
+		printAssigneeSummary(u, tasks)
+		showAndReviewTasks(tasks)
+
+		if i+1 < len(users) {
+			fmt.Printf("\nNext up: %s. Press Enter to continue, any other key to stop.\n",
+				users[i+1])
+			if r := readKey(); r != 10 {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	backup = flag.Bool("backup", false,
+		"Back up a full task export at the start of each session.")
+	backupDir = flag.String("backup-dir", os.Getenv("HOME")+"/.taskreview.backups",
+		"Directory to keep backups in.")
+	keepBackups = flag.Int("keep-backups", 10, "Number of backups to keep.")
+)
+
+// listBackups returns the backup files, oldest first.
+func listBackups() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(*backupDir, "backup-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// The timestamp in the name makes them sort chronologically.
+	sort.Strings(files)
+	return files, nil
+}
+
+// backupTasks writes a full export to a timestamped file, and removes the
+// oldest backups beyond the ones to keep.
+func backupTasks() error {
+	out, err := runTask("export")
+	if err != nil {
+		return errors.Wrap(err, "while exporting tasks")
+	}
+	if err := os.MkdirAll(*backupDir, 0700); err != nil {
+		return errors.Wrapf(err, "while creating %q", *backupDir)
+	}
+	name := filepath.Join(*backupDir,
+		"backup-"+time.Now().UTC().Format(stamp)+".json")
+	if err := ioutil.WriteFile(name, out, 0600); err != nil {
+		return errors.Wrapf(err, "while writing %q", name)
+	}
+	lg.Infof("Backed up tasks to %q", name)
+
+	files, err := listBackups()
+	if err != nil {
+		return err
+	}
+	for len(files) > *keepBackups {
+		if err := os.Remove(files[0]); err != nil {
+			return errors.Wrapf(err, "while removing %q", files[0])
+		}
+		lg.Infof("Removed old backup %q", files[0])
+		files = files[1:]
+	}
+	return nil
+}
+
+// restoreTasks re-imports the backup at path, or the latest one if path is
+// empty.
+func restoreTasks(path string) error {
+	if len(path) == 0 {
+		files, err := listBackups()
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return errors.Errorf("no backups found in %q", *backupDir)
+		}
+		path = files[len(files)-1]
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "while reading %q", path)
+	}
+	var tasks []task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return errors.Wrapf(err, "while parsing %q", path)
+	}
+	if *dryRun {
+		boldBlue.Printf("[dry run] Would restore %d tasks from %s:\n", len(tasks), path)
+		for _, t := range tasks {
+			fmt.Printf("  %.8s %-10s %s\n", t.Uuid, t.Status, t.Description)
+		}
+		return nil
+	}
+	if out, err := runTaskInput(data, "import"); err != nil {
+		return errors.Wrapf(err, "while importing %q: %s", path, out)
+	}
+	fmt.Printf("Restored %d tasks from %s\n", len(tasks), path)
+	return nil
+}
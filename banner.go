@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// activity is what's being tracked right now: a started task, or failing
+// that, whatever timewarrior is tracking.
+type activity struct {
+	uuid  string // Empty if timewarrior tracks something which isn't a task.
+	desc  string
+	since time.Time
+}
+
+// currentActivity returns what's being tracked, if anything.
+func currentActivity() (activity, bool) {
+	var act activity
+	for _, t := range db.tasks {
+		if t.Status != "pending" {
+			continue
+		}
+		if since, ok := parseStamp(t.Start); ok && since.After(act.since) {
+			act = activity{t.Uuid, t.Description, since}
+		}
+	}
+	if len(act.uuid) > 0 {
+		return act, true
+	}
+	iv, ok := cachedInterval()
+	if !ok {
+		return act, false
+	}
+	act.since, _ = time.Parse(stamp, iv.Start)
+	tk, ok := iv.taskByUuid()
+	if !ok {
+		// Only time tracked by the hook needs the descriptions.
+		tk, ok = iv.taskOf(descriptions())
+	}
+	if ok {
+		act.uuid, act.desc = tk.Uuid, tk.Description
+	} else {
+		act.desc = fmt.Sprintf("%q", iv.Tags)
+	}
+	return act, true
+}
+
+// printBanner shows what's being tracked, at the top of the list and task
+// screens, along with the keys of the group to jump to it and stop it.
+func printBanner(group string) {
+	act, ok := currentActivity()
+	if !ok {
+		return
+	}
+	color.New(color.BgGreen, color.FgBlack).Printf(" Tracking %s for %s", clip(act.desc, 60),
+		age(time.Since(act.since)))
+	if r, ok := short.keyFor("jump to active", group); ok && len(act.uuid) > 0 {
+		color.New(color.BgGreen, color.FgBlack).Printf("[%c jumps to it] ", r)
+	}
+	if r, ok := short.keyFor("stop active", group); ok {
+		color.New(color.BgGreen, color.FgBlack).Printf("[%c stops it] ", r)
+	}
+	fmt.Println()
+}
+
+// stopActivity stops whatever is being tracked.
+func stopActivity() {
+	act, ok := currentActivity()
+	switch {
+	case !ok:
+		notice = "Nothing is being tracked."
+	case len(act.uuid) > 0:
+		cachedTask(act.uuid).stopTask()
+	default:
+		timew("stop")
+		forgetTracked()
+	}
+}
+
+// jumpToActive returns how far the task being tracked is in the list.
+func jumpToActive(tasks []task, idx int) int {
+	act, ok := currentActivity()
+	if !ok || len(act.uuid) == 0 {
+		notice = "No task is being tracked."
+		return 0
+	}
+	if tasks[idx].Uuid == act.uuid {
+		return 0
+	}
+	if d := nextMatching(tasks, idx, func(t task) bool { return t.Uuid == act.uuid }); d != 0 {
+		return d
+	}
+	notice = fmt.Sprintf("%q is being tracked, but isn't in this list.", clip(act.desc, 40))
+	return 0
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// importBatch imports several tasks as a unit. The prior state of every
+// task is captured first, and if any import fails, the tasks imported so far
+// are put back the way they were.
+func importBatch(tasks []task) error {
+	var imported []task // Prior states of the tasks imported so far.
+	for _, t := range tasks {
+		var orig task
+		if len(t.Uuid) > 0 {
+			var ok bool
+			if orig, ok = db.fresh(t.Uuid); !ok {
+				var err error
+				if orig, err = exportTask(t.Uuid); err != nil {
+					return rollback(imported, err)
+				}
+			}
+		}
+		_, t, ok := t.prepareImport()
+		if !ok {
+			continue
+		}
+		if err := t.importJSON(); err != nil {
+			return rollback(imported, err)
+		}
+		if len(orig.Uuid) > 0 {
+			imported = append(imported, orig)
+		}
+	}
+	return nil
+}
+
+// rollback restores the given tasks to their prior states, after a batch
+// failed with cause, and reports what happened.
+func rollback(prior []task, cause error) error {
+	lg.Errorf("Batch import failed: %v. Rolling back %d tasks.", cause, len(prior))
+	fmt.Println()
+	boldRed.Printf("Import failed: %v\n", cause)
+
+	var failed int
+	for i := len(prior) - 1; i >= 0; i-- {
+		orig := prior[i]
+		err := func() error {
+			// Our import changed the mod time, so pick up the new one.
+			cur, err := exportTask(orig.Uuid)
+			if err != nil {
+				return err
+			}
+			orig.Modified = cur.Modified
+			return orig.importJSON()
+		}()
+		if err != nil {
+			failed++
+			lg.Errorf("Unable to roll back task %v: %v", orig.Uuid, err)
+			boldRed.Printf("  Unable to roll back: %s: %v\n", orig.Description, err)
+			continue
+		}
+		fmt.Printf("  Rolled back: %s\n", orig.Description)
+	}
+	fmt.Printf("Rolled back %d of %d tasks. Press any key to continue.\n",
+		len(prior)-failed, len(prior))
+	readKey()
+	return errors.Wrapf(cause, "batch import rolled back")
+}
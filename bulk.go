@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// bulkKinds are what a bulk action can pick the listed tasks by, keyed by
+// the list action which starts the chord.
+var bulkKinds = map[string]struct{ group, header string }{
+	"bulk by project": {"project", "Project"},
+	"bulk by user":    {"user", "User"},
+	"bulk by tag":     {"tag", "Tag"},
+}
+
+// bulkAction applies an action to every listed task in a project, or of a
+// user or tag, in one chord: the list key for the kind, the shortcut of the
+// project, user or tag, and then the action. The count is confirmed first,
+// and the changes are imported as a batch.
+func bulkAction(kind string, tasks []task) {
+	k := bulkKinds[kind]
+	fmt.Println()
+	ch := showAndGetResponse(k.header, k.group)
+	name, ok := short.MapsTo(ch, k.group)
+	if !ok {
+		return
+	}
+	var idx []int
+	for i, tk := range tasks {
+		var match bool
+		switch k.group {
+		case "project":
+			match = tk.Project == name || strings.HasPrefix(tk.Project, name+".")
+		case "user":
+			match = tk.userTag() == "@"+name
+		case "tag":
+			match = tk.hasTag(name)
+		}
+		if match {
+			idx = append(idx, i)
+		}
+	}
+	if len(idx) == 0 {
+		fmt.Printf("No listed tasks for %s. Press any key to continue.\n", name)
+		readKey()
+		return
+	}
+
+	ch = showAndGetResponse(fmt.Sprintf("%d tasks for %s", len(idx), name), "bulk")
+	action, ok := short.MapsTo(ch, "bulk")
+	if !ok {
+		return
+	}
+	// Colors and assignees are set the way triage rules set them.
+	var rule triageRule
+	var what string
+	switch action {
+	case "reviewed":
+		what = "Mark reviewed"
+	case "done":
+		what = "Mark done"
+	case "color":
+		ch := showAndGetResponse("Color", "color")
+		if rule.Set.Color, ok = short.MapsTo(ch, "color"); !ok {
+			return
+		}
+		what = "Color " + rule.Set.Color
+	case "assign":
+		ch := showAndGetResponse("Assign To", "user")
+		if rule.Set.Assignee, ok = short.MapsTo(ch, "user"); !ok {
+			return
+		}
+		what = "Assign to @" + rule.Set.Assignee
+	default:
+		return
+	}
+	fmt.Printf("\n%s %d listed tasks for %s? [y/N] ", what, len(idx), name)
+	if r := readKey(); r != 'y' && r != 'Y' {
+		fmt.Println()
+		return
+	}
+	fmt.Println()
+
+	var updates []task
+	var updated []int
+	var tracked bool
+	for _, i := range idx {
+		tk := tasks[i].clone()
+		switch action {
+		case "reviewed":
+			if tk.isReviewed() {
+				continue
+			}
+			tk.markReviewed()
+		case "done":
+			if tk.Status == "completed" {
+				continue
+			}
+			tk.Status = "completed"
+			tk.Completed = time.Now().UTC().Format(stamp)
+			if len(tk.Start) > 0 {
+				tk = tk.full()
+				tracked = tk.stopTracking() || tracked
+			}
+		default:
+			if tk = rule.apply(tk); !withinLimit(tk) {
+				continue
+			}
+		}
+		updates = append(updates, tk)
+		updated = append(updated, i)
+	}
+	if err := importBatch(updates); err != nil {
+		return
+	}
+	if tracked {
+		timew("stop")
+	}
+	for _, i := range updated {
+		tasks[i] = cachedTask(tasks[i].Uuid)
+	}
+}
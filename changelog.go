@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// showChangelog renders the reviewed, completed tasks matching the filter as
+// a Markdown changelog grouped by project, to paste into release notes or a
+// status email. Without a completed window in the filter, it asks for one.
+func showChangelog(filter string) {
+	if _, window := splitWindow(filter); len(window) == 0 {
+		filter = askWindow(filter)
+		if _, window = splitWindow(filter); len(window) == 0 {
+			return
+		}
+	}
+	tasks, err := getTasks(filter)
+	if err != nil {
+		lg.Fatalf("While getting tasks for filter %q: %v", filter, err)
+	}
+	byProject := make(map[string][]task)
+	for _, tk := range tasks {
+		if tk.Status != "completed" || !tk.isReviewed() {
+			continue
+		}
+		p := tk.Project
+		if len(p) == 0 {
+			p = unassigned
+		}
+		byProject[p] = append(byProject[p], tk)
+	}
+	projects := make([]string, 0, len(byProject))
+	for p := range byProject {
+		projects = append(projects, p)
+	}
+	sort.Strings(projects)
+
+	var doc bytes.Buffer
+	_, window := splitWindow(filter)
+	fmt.Fprintf(&doc, "## Changes in the last %s\n", window)
+	for _, p := range projects {
+		fmt.Fprintf(&doc, "\n### %s\n", p)
+		for _, tk := range byProject[p] {
+			fmt.Fprintf(&doc, "- %s", oneLine(tk.Description))
+			if u := tk.userTag(); len(u) > 0 {
+				fmt.Fprintf(&doc, " (%s)", u)
+			}
+			fmt.Fprintln(&doc)
+		}
+	}
+	if len(projects) == 0 {
+		fmt.Fprintf(&doc, "\nNo reviewed tasks were completed.\n")
+	}
+	showDoc(doc.Bytes())
+}
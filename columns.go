@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// column is a column of the task list, as configured in the settings.
+type column struct {
+	Name  string `json:"name"`
+	Width int    `json:"width,omitempty"`
+}
+
+var defaultColumns = []column{
+	{Name: "status"}, {Name: "depends"}, {Name: "assignee"}, {Name: "project"}, {Name: "due"},
+	{Name: "urgency"}, {Name: "description"}, {Name: "color"},
+	{Name: "pomodoros"}, {Name: "notes"}, {Name: "reviewers"},
+}
+
+// fit truncates s to width.
+func fit(s string, width int) string {
+	if len(s) > width {
+		return s[:width]
+	}
+	return s
+}
+
+// columnPrinters render each column of a task, at the given width. Columns
+// which don't take a width ignore it.
+var columnPrinters = map[string]func(tk task, width int){
+	"status": func(tk task, width int) {
+		b := statusBadge(tk)
+		b.c.Printf(" %s ", b.label())
+		if tk.badDates() {
+			color.New(color.BgMagenta, color.FgWhite).Printf("?")
+		} else if tk.isIdle(time.Now()) {
+			color.New(color.BgRed, color.FgWhite).Printf("z")
+		} else if bookmarks[tk.Uuid] {
+			color.New(color.BgYellow, color.FgBlack).Printf("*")
+		} else {
+			fmt.Printf(" ")
+		}
+	},
+	"depends": func(tk task, width int) {
+		color.New(color.FgRed).Printf("%-*s", width, dependsLabel(tk))
+	},
+	"assignee": func(tk task, width int) {
+		color.New(color.BgYellow, color.FgBlack).Printf(" %*s ", width, fit(tk.userTag(), width))
+	},
+	"project": func(tk task, width int) {
+		color.New(color.BgCyan).Printf(" %*s ", width, fit(tk.Project, width))
+	},
+	"due": func(tk task, width int) {
+		if finished, ok := tk.ended(); ok && tk.Status == "completed" {
+			completedColor().Printf(" %-*s", width, fit("done "+finished.Local().Format("Jan 02"), width))
+			return
+		}
+		due, overdue := tk.dueLabel(time.Now())
+		if overdue {
+			color.New(color.BgRed, color.FgWhite).Printf(" %-*s", width, fit(due, width))
+		} else {
+			fmt.Printf(" %-*s", width, fit(due, width))
+		}
+	},
+	"urgency": func(tk task, width int) {
+		fmt.Printf(" %*.1f", width, tk.Urgency)
+	},
+	"tags": func(tk task, width int) {
+		var tags []string
+		for _, t := range tk.Tags {
+			if isNormalTag(t) {
+				tags = append(tags, t)
+			}
+		}
+		color.New(color.FgMagenta).Printf(" %-*s", width, fit(strings.Join(tags, " "), width))
+	},
+	"description": func(tk task, width int) {
+		if tk.Status == "completed" {
+			completedColor().Printf(" %-*s", width, fit(tk.Description, width))
+			return
+		}
+		color.New(color.BgWhite, color.FgBlack).Printf(" %-*s", width, fit(tk.Description, width))
+	},
+	"color": func(tk task, width int) {
+		ptag := tk.colorTag()
+		if cfg.Icons {
+			stateDot(ptag).Printf(" ●")
+			return
+		}
+		stateColor(ptag).Printf(" %-*s ", width, fit(stateBadge(ptag), width))
+	},
+	"pomodoros": func(tk task, width int) {
+		if tk.Pomodoros > 0 {
+			color.New(color.FgRed).Printf(" %dp", tk.Pomodoros)
+		}
+	},
+	"notes": func(tk task, width int) {
+		if n := tk.noteCount(); n > 0 {
+			color.New(color.FgCyan).Printf(" %dn", n)
+		}
+	},
+	"reviewers": func(tk task, width int) {
+		if len(trackedReviewers()) <= 1 {
+			return
+		}
+		done, pending := tk.signoffs()
+		for _, r := range done {
+			color.New(color.BgGreen, color.FgBlack).Printf(" %s ", reviewerName(r))
+		}
+		for _, r := range pending {
+			color.New(color.BgRed, color.FgWhite).Printf(" %s ", reviewerName(r))
+		}
+	},
+}
+
+// completedColor dims completed tasks, so they stand apart from the pending
+// ones in a list with both.
+func completedColor() *color.Color {
+	c := color.New(color.FgHiBlack)
+	if cfg.StrikeCompleted {
+		c.Add(color.CrossedOut)
+	}
+	return c
+}
+
+// defaultWidths are used for configured columns which don't set a width.
+var defaultWidths = map[string]int{
+	"depends": 4, "assignee": 13, "project": 12, "due": 11, "urgency": 5, "tags": 20,
+	"description": 60, "color": 10,
+}
+
+func (c column) width() int {
+	if c.Width > 0 {
+		return c.Width
+	}
+	return defaultWidths[c.Name]
+}
+
+// columnWidth returns the width of the named column, if it's shown.
+func columnWidth(name string) (int, bool) {
+	for _, c := range cfg.Columns {
+		if c.Name == name {
+			return c.width(), true
+		}
+	}
+	return 0, false
+}
+
+func printColumns(tk task) {
+	for _, c := range cfg.Columns {
+		columnPrinters[c.Name](tk, c.width())
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/manishrjain/keys"
+	"github.com/pkg/errors"
+)
+
+// command is a subcommand, with flags of its own, which go after it. The
+// global flags go before it.
+type command struct {
+	name    string
+	summary string
+	flags   *flag.FlagSet
+	run     func() error
+}
+
+var commands = []command{
+	{"review", "Review the tasks matching the filter. The default.", reviewFlags, runReview},
+	{"report", "Print the per-project, per-user and per-color breakdown.", reportFlags, runReport},
+	{"add", "Add a task.", addFlags, runAdd},
+	{"config", "Print the settings, or export or import them with the shortcuts.", configFlags, runConfig},
+	{"sync", "Sync taskwarrior, and report how many tasks changed.", syncFlags, runSync},
+	{"stats", "Print the pending, unreviewed, disputed and overdue counts.", statsFlags, runStats},
+	{"export", "Export the tasks as csv, json, markdown or html.", exportFlags, runExport},
+	{"remind", "Print or send how many tasks need a review, for cron.", remindFlags, runRemind},
+	{"invoice", "Sum the time tracked on billable tasks per client and project, as csv.", invoiceFlags, runInvoice},
+	{"timesheet", "Write the time tracked per project on each day of the past week.", timesheetFlags, runTimesheet},
+	{"restore", "Re-import the latest backup, or the one at the given path.", restoreFlags, func() error {
+		return restoreTasks(restoreFlags.Arg(0))
+	}},
+	{"export-config", "Same as config -export.", exportConfigFlags, func() error {
+		return exportConfig(exportConfigFlags.Arg(0))
+	}},
+	{"import-config", "Same as config -import.", importConfigFlags, func() error {
+		return importConfig(importConfigFlags.Arg(0))
+	}},
+}
+
+var (
+	reviewFlags = commandFlags("review", filterArg)
+
+	reportFlags  = commandFlags("report", filterArg)
+	reportWindow = reportFlags.String("window", "",
+		"Report on the tasks completed in this window, for e.g. 2w, 30d, 3m or all.")
+
+	addFlags   = commandFlags("add", "description")
+	addProject = addFlags.String("project", "", "Project of the task.")
+	addUser    = addFlags.String("user", "", "Who to assign the task to.")
+	addColor   = addFlags.String("color", "", "Color of the task. Defaults to fix_state.")
+	addTags    = addFlags.String("tags", "", "Comma separated tags to add.")
+
+	configFlags  = commandFlags("config", "")
+	configExport = configFlags.String("export", "",
+		"Bundle the shortcuts and settings into this file.")
+	configImport = configFlags.String("import", "",
+		"Put the shortcuts and settings bundled in this file in place.")
+
+	syncFlags = commandFlags("sync", "")
+	syncQuiet = syncFlags.Bool("q", false, "Don't print the output of task sync.")
+
+	restoreFlags      = commandFlags("restore", "[path]")
+	exportConfigFlags = commandFlags("export-config", "path")
+	importConfigFlags = commandFlags("import-config", "path")
+)
+
+// lockFree commands don't need the lock, since they don't change anything,
+// and run while a review is going on.
+var lockFree = map[string]bool{
+	"completion": true, "complete": true, "stats": true, "export": true, "remind": true,
+	"invoice": true, "timesheet": true,
+}
+
+// Exit codes, which scripts can branch on.
+const (
+	exitClear      = 0 // Nothing needs a review.
+	exitUnreviewed = 1 // Some tasks need a review.
+	exitError      = 2
+)
+
+// errUnreviewed is returned by the non-interactive commands when some of the
+// tasks they looked at need a review. It isn't reported, only exited with.
+var errUnreviewed = errors.New("tasks need a review")
+
+// filterArg is the usage of commands whose args are a filter.
+const filterArg = "[filter]"
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [command flags] [args]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-14s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(out, "\nRun a command with -h for its flags. Flags:\n")
+	flag.PrintDefaults()
+}
+
+// commandFlags returns the flag set for the command, with usage naming what
+// its args are.
+func commandFlags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags] %s [command flags] %s\n", os.Args[0], name, args)
+		fs.PrintDefaults()
+	}
+	argUsage[fs] = args
+	return fs
+}
+
+// argUsage is what the args of each command are, by its flags.
+var argUsage = make(map[*flag.FlagSet]string)
+
+// commandFilter returns the filter given by -filter-file, -f, and as the
+// command's args.
+func commandFilter(fs *flag.FlagSet) string {
+	var filter string
+	if len(*filterFile) > 0 {
+		var err error
+		if filter, err = readFilterFile(*filterFile); err != nil {
+			lg.Fatalf("%v", err)
+		}
+	}
+	filter = addTerms(filter, *cmdfilter)
+	return addTerms(filter, strings.Join(fs.Args(), " "))
+}
+
+// runReview runs the interactive review, over the filter given by -f, or
+// as args, narrowed down to the tasks listed by -uuids.
+func runReview() error {
+	filter := commandFilter(reviewFlags)
+	if len(*uuidsPath) > 0 {
+		uuids, err := readUuids(*uuidsPath)
+		if err != nil {
+			return err
+		}
+		if len(uuids) == 0 {
+			return errors.New("no uuids to review")
+		}
+		filter = addTerms(filter, strings.Join(uuids, " or "))
+		if *uuidsPath == "-" {
+			if err := reopenTerminal(); err != nil {
+				return err
+			}
+		}
+	}
+
+	loadSettings()
+	if *backup {
+		if err := backupTasks(); err != nil {
+			return errors.Wrapf(err, "while backing up")
+		}
+	}
+	lg.Infof("Starting session with filter: %q", filter)
+	if err := db.load(); err != nil {
+		return err
+	}
+	short = newKeymap(keys.ParseConfig(*config))
+	generateMappings()
+
+	fmt.Println("Taskreview version 0.1")
+	singleCharMode()
+	defer lineInputMode()
+	if changes := short.stabilize(); len(changes) > 0 {
+		boldRed.Println("Shortcuts changed to keep existing ones stable:")
+		for _, c := range changes {
+			fmt.Println(" " + c)
+		}
+		fmt.Println("Press any key to continue.")
+		readKey()
+	}
+	escalate()
+	if cfg.Archive.Auto {
+		archiveTasks("", true)
+	}
+	filter = offerResume(filter)
+	for {
+		cur.Filter = filter
+		saveSession()
+		filter = runShell(filter)
+		if filter == "-1" {
+			break
+		}
+		filter = strings.Trim(filter, " \n")
+	}
+	endSession()
+	short.Persist(*config)
+	short.saveLast()
+	printProfile()
+	lg.Infof("Session ended.")
+	return nil
+}
+
+func runReport() error {
+	loadSettings()
+	filter := commandFilter(reportFlags)
+	if len(*reportWindow) > 0 {
+		filter = withWindow(filter, *reportWindow)
+	}
+	return printReport(filter)
+}
+
+func runAdd() error {
+	loadSettings()
+	t := task{
+		Description: strings.Join(addFlags.Args(), " "),
+		Project:     *addProject,
+		Status:      "pending",
+	}
+	if len(t.Description) == 0 {
+		return errors.New("a description is needed")
+	}
+	state := *addColor
+	if len(state) == 0 {
+		state = cfg.FixState
+	}
+	if !isStateTag(state) {
+		return errors.Errorf("unknown color %q", state)
+	}
+	t.Tags = append(t.Tags, state)
+	if len(*addUser) > 0 {
+		t.Tags = append(t.Tags, "@"+strings.TrimPrefix(*addUser, "@"))
+	}
+	for _, tag := range strings.Split(*addTags, ",") {
+		if tag = strings.TrimSpace(tag); len(tag) > 0 {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+	t.doImport()
+	fmt.Printf("Added: %s\n", t.Description)
+	return nil
+}
+
+func runConfig() error {
+	switch {
+	case len(*configExport) > 0:
+		return exportConfig(*configExport)
+	case len(*configImport) > 0:
+		return importConfig(*configImport)
+	}
+	loadSettings()
+	fmt.Printf("# Settings from %s, over the defaults.\n", *settingsPath)
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "while marshalling settings")
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func runSync() error {
+	before, err := modifiedTimes()
+	if err != nil {
+		return err
+	}
+	out, err := runTask("sync")
+	if !*syncQuiet {
+		os.Stdout.Write(out)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "while running task sync")
+	}
+	after, err := modifiedTimes()
+	if err != nil {
+		return err
+	}
+	var changed int
+	for uuid, mod := range after {
+		if before[uuid] != mod {
+			changed++
+		}
+	}
+	fmt.Printf("%d tasks changed.\n", changed)
+	return nil
+}
+
+// modifiedTimes exports all the tasks, and returns when each was modified.
+func modifiedTimes() (map[string]string, error) {
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+	mods := make(map[string]string, len(db.tasks))
+	for _, t := range db.tasks {
+		mods[t.Uuid] = t.Modified
+	}
+	return mods, nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var completionCache = flag.String("completion-cache", os.Getenv("HOME")+"/.taskreview.completion",
+	"Path to cache the projects, tags and users in, for shell completion.")
+
+var (
+	completionFlags = commandFlags("completion", "bash|zsh|fish")
+	completeFlags   = commandFlags("complete", "-- [words]")
+)
+
+// The completion commands are added here, as they refer to commands.
+func init() {
+	commands = append(commands,
+		command{"completion", "Print the completion script for bash, zsh or fish.", completionFlags, runCompletion},
+		command{"complete", "Print the completions after the words, for the completion scripts.", completeFlags, runComplete})
+}
+
+// writeCompletionCache saves the filter terms for the projects, tags and
+// users of the pending tasks, one per line, for completion to read without
+// exporting.
+func writeCompletionCache(tasks []*task) {
+	seen := make(map[string]bool)
+	for _, t := range tasks {
+		if t.Status != "pending" {
+			continue
+		}
+		// Parent projects match their subprojects, so complete those too.
+		for p := t.Project; len(p) > 0; {
+			seen["project:"+p] = true
+			i := strings.LastIndex(p, ".")
+			if i < 0 {
+				break
+			}
+			p = p[:i]
+		}
+		for _, tag := range t.Tags {
+			if len(tag) > 0 {
+				seen["+"+tag] = true
+			}
+		}
+	}
+	words := make([]string, 0, len(seen))
+	for w := range seen {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	data := []byte(strings.Join(words, "\n") + "\n")
+	if err := ioutil.WriteFile(*completionCache, data, 0644); err != nil {
+		lg.Errorf("While saving completions to %q: %v", *completionCache, err)
+	}
+}
+
+// filterWords returns the cached filter terms, exporting the tasks to cache
+// them if there aren't any yet.
+func filterWords() []string {
+	f, err := os.Open(*completionCache)
+	if os.IsNotExist(err) {
+		if err := db.load(); err != nil {
+			lg.Errorf("%v", err)
+			return nil
+		}
+		f, err = os.Open(*completionCache)
+	}
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var words []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		words = append(words, s.Text())
+	}
+	return words
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// skipFlags returns how many of the words are flags of fs, along with their
+// values. If the last flag is still waiting for its value, it's returned.
+func skipFlags(fs *flag.FlagSet, words []string) (int, *flag.Flag) {
+	var i int
+	for i < len(words) && strings.HasPrefix(words[i], "-") && words[i] != "--" {
+		name := strings.TrimLeft(words[i], "-")
+		i++
+		f := fs.Lookup(name)
+		if f == nil || isBoolFlag(f) || strings.Contains(name, "=") {
+			continue
+		}
+		if i == len(words) {
+			return i, f
+		}
+		i++
+	}
+	return i, nil
+}
+
+func flagNames(fs *flag.FlagSet) []string {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, "-"+f.Name) })
+	return names
+}
+
+// completions returns what could come after the words, already typed after
+// the program name.
+func completions(words []string) []string {
+	i, pending := skipFlags(flag.CommandLine, words)
+	if pending != nil {
+		if pending.Name == "f" {
+			return filterWords()
+		}
+		return nil
+	}
+	if i == len(words) {
+		var res []string
+		for _, c := range commands {
+			if c.name != "complete" {
+				res = append(res, c.name)
+			}
+		}
+		return append(res, flagNames(flag.CommandLine)...)
+	}
+	cmd, ok := findCommand(words[i])
+	if !ok {
+		return nil
+	}
+	j, pending := skipFlags(cmd.flags, words[i+1:])
+	if pending != nil {
+		return nil
+	}
+	var res []string
+	if j == len(words[i+1:]) {
+		res = flagNames(cmd.flags)
+	}
+	if argUsage[cmd.flags] == filterArg {
+		res = append(res, filterWords()...)
+	}
+	return res
+}
+
+func runComplete() error {
+	for _, w := range completions(completeFlags.Args()) {
+		fmt.Println(w)
+	}
+	return nil
+}
+
+func runCompletion() error {
+	script, ok := completionScripts[completionFlags.Arg(0)]
+	if !ok {
+		return errors.Errorf("no completion for shell %q, only bash, zsh and fish",
+			completionFlags.Arg(0))
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// completionScripts ask the complete command for candidates. Bash breaks
+// words at colons and @, so its script splits the line itself, and drops
+// what comes before those from the candidates.
+var completionScripts = map[string]string{
+	"bash": `# Load with: source <(taskreview completion bash)
+_taskreview() {
+  local line=${COMP_LINE:0:COMP_POINT}
+  local -a words
+  read -ra words <<< "$line"
+  local cur=
+  if [[ $line != *[[:space:]] ]]; then
+    cur=${words[${#words[@]}-1]}
+    unset 'words[${#words[@]}-1]'
+  fi
+  cur=${cur#[\"\']}
+  local IFS=$'\n'
+  COMPREPLY=($(compgen -W "$(taskreview complete -- "${words[@]:1}" 2>/dev/null)" -- "$cur"))
+  # Bash only replaces what follows the last of these in the word.
+  local prefix=${cur%"${cur##*[:=@]}"}
+  COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+}
+complete -o default -F _taskreview taskreview
+`,
+	"zsh": `#compdef taskreview
+# Load with: source <(taskreview completion zsh)
+_taskreview() {
+  local -a candidates
+  candidates=(${(f)"$(taskreview complete -- ${words[2,CURRENT-1]} 2>/dev/null)"})
+  compadd -a candidates
+}
+compdef _taskreview taskreview
+`,
+	"fish": `# Load with: taskreview completion fish | source
+function __taskreview_complete
+    taskreview complete -- (commandline -opc)[2..-1] 2>/dev/null
+end
+complete -c taskreview -f -a '(__taskreview_complete)'
+`,
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// taskField is a field of a task which can differ between two versions.
+type taskField struct {
+	name string
+	show func(t task) string
+	copy func(dst *task, src task)
+}
+
+var taskFields = []taskField{
+	{"Description",
+		func(t task) string { return t.Description },
+		func(dst *task, src task) { dst.Description = src.Description }},
+	{"Project",
+		func(t task) string { return t.Project },
+		func(dst *task, src task) { dst.Project = src.Project }},
+	{"Status",
+		func(t task) string { return t.Status },
+		func(dst *task, src task) { dst.Status, dst.Completed = src.Status, src.Completed }},
+	{"Due",
+		func(t task) string { return t.Due },
+		func(dst *task, src task) { dst.Due = src.Due }},
+	{"Client",
+		func(t task) string { return t.Client },
+		func(dst *task, src task) { dst.Client = src.Client }},
+	{"Estimate",
+		func(t task) string { return t.Estimate },
+		func(dst *task, src task) { dst.Estimate = src.Estimate }},
+	{"Start",
+		func(t task) string { return t.Start },
+		func(dst *task, src task) { dst.Start = src.Start }},
+	{"Tags",
+		func(t task) string { return strings.Join(t.Tags, " ") },
+		func(dst *task, src task) { dst.Tags = append([]string{}, src.Tags...) }},
+	{"Annotations",
+		func(t task) string { return fmt.Sprintf("%d", len(t.Annotations)) },
+		func(dst *task, src task) { dst.Annotations = append([]annotation{}, src.Annotations...) }},
+	{"Reviews",
+		func(t task) string { return t.ReviewedBy + " " + t.ReviewedAt },
+		func(dst *task, src task) {
+			dst.ReviewedBy, dst.ReviewedAt, dst.Reviewed = src.ReviewedBy, src.ReviewedAt, src.Reviewed
+		}},
+	{"Pomodoros",
+		func(t task) string { return fmt.Sprintf("%d", t.Pomodoros) },
+		func(dst *task, src task) { dst.Pomodoros = src.Pomodoros }},
+}
+
+// diffFields returns the fields which differ between the two versions.
+func diffFields(a, b task) []taskField {
+	var diff []taskField
+	for _, f := range taskFields {
+		if f.show(a) != f.show(b) {
+			diff = append(diff, f)
+		}
+	}
+	return diff
+}
+
+func clip(s string, n int) string {
+	if len(s) > n {
+		return s[:n-3] + "..."
+	}
+	return s
+}
+
+// snapshots holds the last versions of each task fetched during the session,
+// by uuid, oldest first. It lets us find the version an edit started from.
+// Two are kept, as the check before an import fetches theirs on top of it.
+var snapshots = make(map[string][]task)
+
+const keepSnapshots = 2
+
+// remember snapshots the tasks. Edits modify tags in place, so the slices
+// are copied. Tasks without their annotations would lose them in a merge, so
+// aren't kept.
+func remember(tasks []task) {
+	for _, t := range tasks {
+		if t.partial {
+			continue
+		}
+		t.Tags = append([]string{}, t.Tags...)
+		t.Annotations = append([]annotation{}, t.Annotations...)
+		var kept []task
+		for _, s := range snapshots[t.Uuid] {
+			if s.Modified != t.Modified {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) >= keepSnapshots {
+			kept = kept[len(kept)-keepSnapshots+1:]
+		}
+		snapshots[t.Uuid] = append(kept, t)
+	}
+}
+
+func snapshot(uuid, modified string) (task, bool) {
+	for _, t := range snapshots[uuid] {
+		if t.Modified == modified {
+			return t, true
+		}
+	}
+	return task{}, false
+}
+
+// mergeTags applies the tags added and removed from base to mine, on top of
+// theirs.
+func mergeTags(base, mine, theirs []string) []string {
+	in := func(tags []string, t string) bool {
+		for _, o := range tags {
+			if o == t {
+				return true
+			}
+		}
+		return false
+	}
+	var res []string
+	for _, t := range theirs {
+		if in(base, t) && !in(mine, t) {
+			continue // Removed by me.
+		}
+		res = append(res, t)
+	}
+	for _, t := range mine {
+		if !in(base, t) && !in(res, t) {
+			res = append(res, t) // Added by me.
+		}
+	}
+	return res
+}
+
+// merge3 reapplies the changes made from base to mine, on top of theirs. It
+// returns false if both sides changed the same thing differently.
+func merge3(base, mine, theirs task) (task, bool) {
+	merged := theirs
+	merged.Tags = mergeTags(base.Tags, mine.Tags, theirs.Tags)
+	if len(mine.Annotations) > len(base.Annotations) {
+		merged.Annotations = append(append([]annotation{}, theirs.Annotations...),
+			mine.Annotations[len(base.Annotations):]...)
+	}
+	for _, f := range taskFields {
+		if f.name == "Tags" || f.name == "Annotations" {
+			continue
+		}
+		b, m, t := f.show(base), f.show(mine), f.show(theirs)
+		switch {
+		case m == b || m == t:
+			// Nothing of mine to apply.
+		case t == b:
+			f.copy(&merged, mine)
+		default:
+			return theirs, false
+		}
+	}
+
+	// Both sides picking a different color, or assignee, is a true conflict.
+	var states, users int
+	for _, t := range merged.Tags {
+		if isStateTag(t) {
+			states++
+		}
+		if strings.HasPrefix(t, "@") {
+			users++
+		}
+	}
+	if states > 1 || users > 1 {
+		return theirs, false
+	}
+	return merged, true
+}
+
+// resolveConflict is run when the task got modified externally, since
+// mine was fetched. It shows how the two versions differ, and lets the user
+// keep theirs, keep mine, or merge field by field. It returns the task to
+// import, or false if there's nothing to import.
+func resolveConflict(mine, theirs task) (task, bool) {
+	diff := diffFields(mine, theirs)
+	fmt.Println()
+	color.New(color.BgRed, color.FgWhite).Printf(
+		" Task's mod time has changed [%q -> %q]. ", mine.Modified, theirs.Modified)
+	fmt.Println()
+	if len(diff) == 0 {
+		// Only the mod time changed, so there's nothing to lose.
+		mine.Modified = theirs.Modified
+		return mine, true
+	}
+
+	fmt.Printf("\n%-12s %-40s %-40s\n", "Field", "Mine", "Theirs")
+	for _, f := range diff {
+		fmt.Printf("%-12s ", f.name)
+		boldGreen.Printf("%-40s ", clip(f.show(mine), 40))
+		boldRed.Printf("%-40s\n", clip(f.show(theirs), 40))
+	}
+	fmt.Printf("\nm keep mine, t take theirs, f merge field by field, any other key to cancel.\n")
+
+	switch readKey() {
+	case 'm':
+		mine.Modified = theirs.Modified
+		return mine, true
+	case 't':
+		return theirs, false
+	case 'f':
+		merged := theirs
+		for _, f := range diff {
+			fmt.Printf("%s: m for ", f.name)
+			boldGreen.Printf("%q", clip(f.show(mine), 40))
+			fmt.Printf(", any other key for ")
+			boldRed.Printf("%q", clip(f.show(theirs), 40))
+			fmt.Println()
+			if readKey() == 'm' {
+				f.copy(&merged, mine)
+			}
+		}
+		return merged, true
+	}
+	return theirs, false
+}
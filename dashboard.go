@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+type dashColumn struct {
+	label string
+	match func(tk task) bool
+}
+
+// dashColumns returns the columns of the dashboard: one per state, plus
+// tasks without one and disputed tasks. Each cell holds the tasks of the
+// row's assignee which match the column.
+func dashColumns() []dashColumn {
+	var cols []dashColumn
+	for _, s := range cfg.States {
+		name := s.Name
+		cols = append(cols, dashColumn{stateBadge(name),
+			func(tk task) bool { return tk.colorTag() == name }})
+	}
+	return append(cols,
+		dashColumn{"none", func(tk task) bool { return len(tk.colorTag()) == 0 }},
+		dashColumn{"disputed", func(tk task) bool { return tk.isDisputed() }})
+}
+
+const unassigned = "(none)"
+
+// cellTasks returns the tasks assigned to user which fall in column col.
+func cellTasks(tasks []task, user string, col int) []task {
+	var res []task
+	for _, tk := range tasks {
+		u := tk.userTag()
+		if len(u) == 0 {
+			u = unassigned
+		}
+		if u == user && dashColumns()[col].match(tk) {
+			res = append(res, tk)
+		}
+	}
+	return res
+}
+
+func printDashboard(tasks []task, users []string, row, col int) {
+	clear()
+	fmt.Printf("%-16s", "")
+	for _, c := range dashColumns() {
+		fmt.Printf(" %9s", c.label)
+	}
+	fmt.Println()
+
+	for r, u := range users {
+		color.New(color.BgYellow, color.FgBlack).Printf(" %14s ", u)
+		for c := range dashColumns() {
+			n := len(cellTasks(tasks, u, c))
+			if r == row && c == col {
+				color.New(color.BgWhite, color.FgBlack).Printf(" %9d", n)
+			} else if n == 0 {
+				fmt.Printf(" %9s", ".")
+			} else {
+				fmt.Printf(" %9d", n)
+			}
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+	fmt.Println("Move with h/j/k/l or arrow keys. Enter to review the cell, q to go back.")
+}
+
+// readDirection reads a key, translating arrow key escape sequences into
+// their h/j/k/l equivalents.
+func readDirection() rune {
+	r := readKey()
+	if r != 27 {
+		return r
+	}
+	if readKey() != '[' {
+		return r
+	}
+	switch readKey() {
+	case 'A':
+		return 'k'
+	case 'B':
+		return 'j'
+	case 'C':
+		return 'l'
+	case 'D':
+		return 'h'
+	}
+	return r
+}
+
+// showDashboard shows an assignees x colors matrix of task counts for the
+// filter, and reviews the tasks in whichever cell is picked.
+func showDashboard(filter string) {
+	var row, col int
+	var tasks []task
+	var users []string
+	refresh := func() {
+		var err error
+		tasks, err = getTasks(filter)
+		if err != nil {
+			lg.Fatalf("While getting tasks for filter %q: %v", filter, err)
+		}
+		users = append(assignees(tasks), unassigned)
+		if row >= len(users) {
+			row = len(users) - 1
+		}
+	}
+
+	refresh()
+	for {
+		printDashboard(tasks, users, row, col)
+		switch readDirection() {
+		case 'h':
+			if col > 0 {
+				col--
+			}
+		case 'l':
+			if col < len(dashColumns())-1 {
+				col++
+			}
+		case 'k':
+			if row > 0 {
+				row--
+			}
+		case 'j':
+			if row < len(users)-1 {
+				row++
+			}
+		case 10: // Enter
+			if cell := cellTasks(tasks, users[row], col); len(cell) > 0 {
+				clear()
+				showAndReviewTasks(cell)
+				refresh()
+			}
+		case 'q':
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// printDurations prints the time per key, the longest first.
+func printDurations(header string, times map[string]time.Duration) {
+	keys := make([]string, 0, len(times))
+	for k := range times {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if times[keys[i]] != times[keys[j]] {
+			return times[keys[i]] > times[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	boldBlue.Printf("%-50s %s\n", header, "Time")
+	for _, k := range keys {
+		fmt.Printf("%-50s %s\n", clip(k, 50), age(times[k]))
+	}
+	fmt.Println()
+}
+
+// showDayTime shows where the time tracked today went, by task, project and
+// color, until a key is pressed.
+func showDayTime() {
+	clear()
+	defer func() {
+		fmt.Println("Press any key to go back.")
+		readKey()
+	}()
+
+	ivs, err := exportIntervals(":day")
+	if err != nil {
+		boldRed.Printf("%v\n\n", err)
+		return
+	}
+	now := time.Now()
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	byTask := make(map[string]time.Duration)
+	byProject := make(map[string]time.Duration)
+	byColor := make(map[string]time.Duration)
+	byDesc := descriptions()
+	var total time.Duration
+	for _, iv := range ivs {
+		dur := iv.within(midnight, now)
+		total += dur
+		tk, ok := iv.taskOf(byDesc)
+		if !ok {
+			// Time tracked outside of taskwarrior, under its own tags.
+			byTask[strings.Join(iv.Tags, " ")] += dur
+			byProject[unassigned] += dur
+			byColor[unassigned] += dur
+			continue
+		}
+		byTask[tk.Description] += dur
+		project, clr := tk.Project, tk.colorTag()
+		if len(project) == 0 {
+			project = unassigned
+		}
+		if len(clr) == 0 {
+			clr = unassigned
+		}
+		byProject[project] += dur
+		byColor[clr] += dur
+	}
+	if total == 0 {
+		fmt.Print("Nothing tracked today.\n\n")
+		return
+	}
+	boldGreen.Printf("Tracked today: %s\n\n", age(total))
+	printDurations("Task", byTask)
+	printDurations("Project", byProject)
+	printDurations("Color", byColor)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// dependsList is the uuids of the tasks a task depends on. Older versions
+// of taskwarrior export it as a comma separated string, newer ones as an
+// array; both are read.
+type dependsList []string
+
+func (d *dependsList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*d = list
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*d = nil
+	for _, u := range strings.Split(s, ",") {
+		if u = strings.TrimSpace(u); len(u) > 0 {
+			*d = append(*d, u)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes the comma separated form, which every version of
+// taskwarrior imports.
+func (d dependsList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.Join(d, ","))
+}
+
+var (
+	// blocked holds the tasks waiting on a pending task.
+	blocked = make(map[string]bool)
+	// blocking counts the tasks each pending task is holding up.
+	blocking = make(map[string]int)
+)
+
+// computeDepends works out which tasks are blocked, and which are blocking
+// others, across all the tasks. Like taskwarrior, only pending and waiting
+// tasks block.
+func computeDepends(all []*task) {
+	open := make(map[string]bool)
+	for _, tk := range all {
+		if tk.Status == "pending" || tk.Status == "waiting" {
+			open[tk.Uuid] = true
+		}
+	}
+	blocked = make(map[string]bool)
+	blocking = make(map[string]int)
+	for _, tk := range all {
+		if !open[tk.Uuid] {
+			continue
+		}
+		for _, u := range tk.Depends {
+			if open[u] {
+				blocked[tk.Uuid] = true
+				blocking[u]++
+			}
+		}
+	}
+}
+
+// dependsLabel is the marker shown in the list, for a task that's blocked
+// or blocking others.
+func dependsLabel(tk task) string {
+	var label string
+	if blocked[tk.Uuid] {
+		label += "⊘"
+	}
+	if n := blocking[tk.Uuid]; n > 0 {
+		label += fmt.Sprintf("→%d", n)
+	}
+	return label
+}
+
+// printDepends lists what the task depends on, using the descriptions of
+// those in tasks.
+func printDepends(tk task, tasks []task) {
+	if len(tk.Depends) == 0 {
+		return
+	}
+	desc := make(map[string]string)
+	for _, t := range tasks {
+		desc[t.Uuid] = t.Description
+	}
+	for i, u := range tk.Depends {
+		label := "              "
+		if i == 0 {
+			label = "Depends on:   "
+		}
+		fmt.Printf("%s%.8s  %s\n", label, u, desc[u])
+	}
+	if n := blocking[tk.Uuid]; n > 0 {
+		fmt.Printf("Blocking:     %d tasks\n", n)
+	}
+}
+
+// jumpToBlocker moves to the first task in the list which tk depends on.
+func jumpToBlocker(tasks []task, idx int) int {
+	tk := tasks[idx]
+	if len(tk.Depends) == 0 {
+		notice = "This task doesn't depend on any other."
+		return 0
+	}
+	deps := make(map[string]bool)
+	for _, u := range tk.Depends {
+		deps[u] = true
+	}
+	if d := nextMatching(tasks, idx, func(t task) bool { return deps[t.Uuid] }); d != 0 {
+		return d
+	}
+	notice = fmt.Sprintf("Blocked by %.8s, which isn't in this list.", tk.Depends[0])
+	return 0
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// duration is a time.Duration which, in the settings file, can also be
+// written in days or weeks, for e.g. "2d" or "3w".
+type duration time.Duration
+
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return 0, nil
+	}
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if u, ok := unit[s[len(s)-1]]; ok {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * u, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	dur, err := parseDuration(s)
+	*d = duration(dur)
+	return err
+}
+
+// escalation promotes pending tasks in one state to another, once they're
+// due soon, or have sat untouched for too long.
+type escalation struct {
+	From         string   `json:"from"`
+	To           string   `json:"to"`
+	DueWithin    duration `json:"due_within,omitempty"`
+	UntouchedFor duration `json:"untouched_for,omitempty"`
+}
+
+func (e escalation) applies(tk task, now time.Time) bool {
+	if tk.colorTag() != e.From || len(tk.Completed) > 0 {
+		return false
+	}
+	if e.DueWithin > 0 && len(tk.Due) > 0 {
+		if due, err := time.Parse(stamp, tk.Due); err == nil &&
+			due.Sub(now) < time.Duration(e.DueWithin) {
+			return true
+		}
+	}
+	if e.UntouchedFor > 0 && len(tk.Modified) > 0 {
+		if mod, err := time.Parse(stamp, tk.Modified); err == nil &&
+			now.Sub(mod) > time.Duration(e.UntouchedFor) {
+			return true
+		}
+	}
+	return false
+}
+
+// escalate runs the escalation rules over all pending tasks, and applies
+// the resulting color changes once confirmed.
+func escalate() {
+	if len(cfg.Escalations) == 0 {
+		return
+	}
+	tasks, err := getTasks("")
+	if err != nil {
+		lg.Fatalf("While getting all tasks: %v", err)
+	}
+
+	now := time.Now().UTC()
+	var promote []task
+	var to []string
+	for _, tk := range tasks {
+		// The first matching rule wins.
+		for _, e := range cfg.Escalations {
+			if e.applies(tk, now) {
+				promote = append(promote, tk)
+				to = append(to, e.To)
+				break
+			}
+		}
+	}
+	if len(promote) == 0 {
+		return
+	}
+
+	clear()
+	boldRed.Printf("%d tasks are due for escalation:\n\n", len(promote))
+	for i, tk := range promote {
+		stateColor(tk.colorTag()).Printf(" %-10s ", stateBadge(tk.colorTag()))
+		fmt.Print(" -> ")
+		stateColor(to[i]).Printf(" %-10s ", stateBadge(to[i]))
+		fmt.Printf(" %s\n", tk.Description)
+	}
+	fmt.Printf("\nApply them? [y/N] ")
+	if r := readKey(); r != 'y' && r != 'Y' {
+		fmt.Println()
+		return
+	}
+	fmt.Println()
+	for i := range promote {
+		tk := &promote[i]
+		tk.Tags = append(remove(tk.Tags, tk.colorTag()), to[i])
+	}
+	importBatch(promote)
+}
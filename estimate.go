@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// isoDurationExp matches the ISO 8601 durations taskwarrior exports duration
+// UDAs as, for e.g. PT2H30M or P1D.
+var isoDurationExp = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// estimated returns the task's estimate, if it has one.
+func (tk task) estimated() (time.Duration, bool) {
+	if len(tk.Estimate) == 0 {
+		return 0, false
+	}
+	dur, err := parseEstimate(tk.Estimate)
+	if err != nil {
+		lg.Debugf("While parsing estimate of task %v: %v", tk.Uuid, err)
+		return 0, false
+	}
+	return dur, dur > 0
+}
+
+// parseEstimate parses an estimate as taskwarrior exports it, or as written
+// in the settings, like 2h or 3d.
+func parseEstimate(s string) (time.Duration, error) {
+	m := isoDurationExp.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "PT" {
+		return parseDuration(s)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var dur time.Duration
+	for i, u := range units {
+		if len(m[i+1]) == 0 {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return 0, errors.Wrapf(err, "while parsing %q", s)
+		}
+		dur += time.Duration(n) * u
+	}
+	return dur, nil
+}
+
+// accuracy shows the time actually spent against the estimate, as a
+// percentage of it.
+func accuracy(estimate, actual time.Duration) string {
+	if estimate <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", 100*float64(actual)/float64(estimate))
+}
+
+// trackedTimes returns the total time tracked on each of the tasks, by uuid,
+// from a single export of timewarrior. As with trackedTime, intervals are
+// matched to tasks by uuid, or else by description.
+func trackedTimes(tasks []task) (map[string]time.Duration, error) {
+	ivs, err := exportIntervals()
+	if err != nil {
+		return nil, err
+	}
+	uuids := make(map[string]bool, len(tasks))
+	byDesc := make(map[string]string, len(tasks))
+	for _, tk := range tasks {
+		uuids[tk.Uuid] = true
+		byDesc[tk.Description] = tk.Uuid
+	}
+	times := make(map[string]time.Duration)
+	now := time.Now().UTC()
+	for _, iv := range ivs {
+		var uuid string
+		for _, tag := range iv.Tags {
+			if uuids[tag] {
+				uuid = tag
+				break
+			}
+		}
+		for _, tag := range iv.Tags {
+			if len(uuid) > 0 {
+				break
+			}
+			uuid = byDesc[tag]
+		}
+		if len(uuid) > 0 {
+			times[uuid] += iv.within(time.Time{}, now)
+		}
+	}
+	return times, nil
+}
+
+// estimateTally sums the estimates and the time actually spent on tasks
+// with both.
+type estimateTally struct {
+	tasks            int
+	estimate, actual time.Duration
+}
+
+// printAccuracy prints how the time spent compared to the estimates, per
+// project and per user, over the tasks with both.
+func printAccuracy(tasks []task) {
+	var estimated []task
+	for _, tk := range tasks {
+		if _, ok := tk.estimated(); ok {
+			estimated = append(estimated, tk)
+		}
+	}
+	if len(estimated) == 0 {
+		return
+	}
+	times, err := trackedTimes(estimated)
+	if err != nil {
+		lg.Errorf("While getting tracked times: %v", err)
+		return
+	}
+	byProject := make(map[string]*estimateTally)
+	byUser := make(map[string]*estimateTally)
+	add := func(m map[string]*estimateTally, key string, estimate, actual time.Duration) {
+		if len(key) == 0 {
+			key = unassigned
+		}
+		t, ok := m[key]
+		if !ok {
+			t = new(estimateTally)
+			m[key] = t
+		}
+		t.tasks++
+		t.estimate += estimate
+		t.actual += actual
+	}
+	for _, tk := range estimated {
+		actual, ok := times[tk.Uuid]
+		if !ok {
+			continue
+		}
+		estimate, _ := tk.estimated()
+		add(byProject, tk.Project, estimate, actual)
+		add(byUser, tk.userTag(), estimate, actual)
+	}
+	if len(byProject) == 0 {
+		return
+	}
+	printEstimates("Project", byProject)
+	printEstimates("User", byUser)
+}
+
+func printEstimates(header string, tallies map[string]*estimateTally) {
+	keys := make([]string, 0, len(tallies))
+	for k := range tallies {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	boldBlue.Printf("%-20s %8s %10s %10s %10s\n", header, "Tasks", "Estimate", "Actual", "Accuracy")
+	for _, k := range keys {
+		t := tallies[k]
+		fmt.Printf("%-20s %8d %9.1fh %9.1fh %10s\n", k, t.tasks, t.estimate.Hours(), t.actual.Hours(),
+			accuracy(t.estimate, t.actual))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	exportFlags  = commandFlags("export", filterArg)
+	exportFormat = exportFlags.String("format", "csv", "Format to export in: csv, json, markdown or html.")
+	exportSort   = exportFlags.String("sort", "urgency", "Sort by urgency, date or color, as in the list.")
+	exportAll    = exportFlags.Bool("all", false,
+		"Include the tasks you've already reviewed, which the list hides by default.")
+	exportOut = exportFlags.String("o", "", "File to write to, instead of stdout.")
+)
+
+// exportField is a field of the exported tasks.
+type exportField struct {
+	name  string
+	value func(tk task) string
+}
+
+var exportFields = []exportField{
+	{"uuid", func(tk task) string { return tk.Uuid }},
+	{"project", func(tk task) string { return tk.Project }},
+	{"assignee", func(tk task) string { return tk.userTag() }},
+	{"color", func(tk task) string { return tk.colorTag() }},
+	{"status", func(tk task) string { return tk.Status }},
+	{"due", func(tk task) string {
+		label, _ := tk.dueLabel(time.Now())
+		return label
+	}},
+	{"urgency", func(tk task) string { return fmt.Sprintf("%.1f", tk.Urgency) }},
+	{"reviewed", func(tk task) string {
+		if tk.isReviewed() {
+			return "yes"
+		}
+		return "no"
+	}},
+	{"description", func(tk task) string { return tk.Description }},
+}
+
+var exportSorts = map[string]int{"urgency": URGENCY, "date": DATE, "color": COLOR}
+
+// runExport writes the tasks matching the filter, picked and sorted as the
+// list does, in the chosen format. It needs no terminal, so it can run from
+// cron.
+func runExport() error {
+	loadSettings()
+	s, ok := exportSorts[*exportSort]
+	if !ok {
+		return errors.Errorf("unknown sort %q", *exportSort)
+	}
+	write, ok := exportWriters[*exportFormat]
+	if !ok {
+		return errors.Errorf("unknown format %q", *exportFormat)
+	}
+	filter := commandFilter(exportFlags)
+	all, err := getTasks(filter)
+	if err != nil {
+		return errors.Wrapf(err, "while getting tasks for filter %q", filter)
+	}
+	var tasks []task
+	for _, tk := range all {
+		if *exportAll || !tk.isReviewed() {
+			tasks = append(tasks, tk)
+		}
+	}
+	sortBy = s
+	sortTasks(tasks)
+
+	out, err := openOutput(*exportOut)
+	if err != nil {
+		return err
+	}
+	err = write(out, tasks)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	for _, tk := range tasks {
+		if !tk.isReviewed() {
+			return errUnreviewed
+		}
+	}
+	return nil
+}
+
+// exportWriters write the tasks in each format.
+var exportWriters = map[string]func(w io.Writer, tasks []task) error{
+	"csv": func(w io.Writer, tasks []task) error {
+		cw := csv.NewWriter(w)
+		var row []string
+		for _, f := range exportFields {
+			row = append(row, f.name)
+		}
+		cw.Write(row)
+		for _, tk := range tasks {
+			row = row[:0]
+			for _, f := range exportFields {
+				row = append(row, f.value(tk))
+			}
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	},
+	"json": func(w io.Writer, tasks []task) error {
+		rows := make([]map[string]string, 0, len(tasks))
+		for _, tk := range tasks {
+			row := make(map[string]string)
+			for _, f := range exportFields {
+				row[f.name] = f.value(tk)
+			}
+			rows = append(rows, row)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	},
+	"markdown": func(w io.Writer, tasks []task) error {
+		var names []string
+		for _, f := range exportFields {
+			names = append(names, f.name)
+		}
+		rows := [][]string{names}
+		for _, tk := range tasks {
+			var cells []string
+			for _, f := range exportFields {
+				cells = append(cells, f.value(tk))
+			}
+			rows = append(rows, cells)
+		}
+		return writeMarkdownTable(w, rows)
+	},
+	"html": func(w io.Writer, tasks []task) error {
+		fmt.Fprintln(w, "<table>\n<tr>")
+		for _, f := range exportFields {
+			fmt.Fprintf(w, "<th>%s</th>", f.name)
+		}
+		fmt.Fprintln(w, "</tr>")
+		for _, tk := range tasks {
+			fmt.Fprint(w, "<tr>")
+			for _, f := range exportFields {
+				fmt.Fprintf(w, "<td>%s</td>", html.EscapeString(f.value(tk)))
+			}
+			if _, err := fmt.Fprintln(w, "</tr>"); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintln(w, "</table>")
+		return err
+	},
+}
+
+// nopCloser leaves stdout open, once written to.
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// openOutput returns the file at path to write to, or stdout without a path.
+// Closing it returns any error in writing out the file.
+func openOutput(path string) (io.WriteCloser, error) {
+	if len(path) == 0 {
+		return nopCloser{os.Stdout}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while creating %q", path)
+	}
+	return f, nil
+}
+
+// writeMarkdownTable writes the rows as a markdown table, the first of them
+// as the header.
+func writeMarkdownTable(w io.Writer, rows [][]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	rule := make([]string, len(rows[0]))
+	for i := range rule {
+		rule[i] = "---"
+	}
+	row := func(cells []string) error {
+		esc := make([]string, len(cells))
+		for i, c := range cells {
+			esc[i] = strings.Replace(oneLine(c), "|", `\|`, -1)
+		}
+		_, err := fmt.Fprintf(w, "| %s |\n", strings.Join(esc, " | "))
+		return err
+	}
+	if err := row(rows[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(rule, " | "))
+	for _, r := range rows[1:] {
+		if err := row(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineBreaks turns line breaks into spaces.
+var lineBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// oneLine keeps text on one line, as a line break would end a markdown table
+// row or list item early.
+func oneLine(s string) string {
+	return lineBreaks.Replace(s)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var filterFile = flag.String("filter-file", "",
+	"File with a filter to start with, combined with -f. It can span lines, with # comments.")
+
+// addTerms adds search terms to the filter. Terms with more than one word
+// are grouped in parentheses, so any "or" in them can't swallow the rest of
+// the filter, whichever order the parts were added in.
+func addTerms(filter, terms string) string {
+	words := strings.Fields(terms)
+	switch len(words) {
+	case 0:
+		return filter
+	case 1:
+		return strings.TrimSpace(filter + " " + words[0])
+	}
+	return strings.TrimSpace(filter + " ( " + strings.Join(words, " ") + " )")
+}
+
+var filterOps = map[string]bool{"and": true, "or": true, "xor": true}
+
+// filterArgs turns the filter into the arguments for taskwarrior, along with
+// the completed window taken out of it. It checks the filter is well formed
+// first, so a bad one doesn't silently match the wrong tasks.
+func filterArgs(filter string) ([]string, string, error) {
+	args, window := splitWindow(filter)
+	if len(window) > 0 {
+		if _, err := parseWindow(window); err != nil {
+			return nil, "", err
+		}
+	}
+	var depth int
+	prev := "("
+	for _, arg := range args {
+		switch {
+		case arg == "(":
+			depth++
+		case arg == ")":
+			depth--
+			if depth < 0 || prev == "(" || filterOps[prev] {
+				return nil, "", fmt.Errorf("misplaced ) in filter %q", filter)
+			}
+		case filterOps[arg]:
+			if prev == "(" || filterOps[prev] {
+				return nil, "", fmt.Errorf("misplaced %q in filter %q", arg, filter)
+			}
+		case arg == "+" || arg == "-":
+			return nil, "", fmt.Errorf("empty tag in filter %q", filter)
+		}
+		prev = arg
+	}
+	if depth != 0 {
+		return nil, "", fmt.Errorf("unbalanced parentheses in filter %q", filter)
+	}
+	if filterOps[prev] {
+		return nil, "", fmt.Errorf("filter %q ends in %q", filter, prev)
+	}
+	return args, window, nil
+}
+
+// readFilterFile reads a filter kept in a file, which can span lines. Blank
+// lines, and everything after a # on a line, are ignored. The filter is
+// checked before it's returned.
+func readFilterFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "while reading filter file")
+	}
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	filter := strings.Join(words, " ")
+	if _, _, err := filterArgs(filter); err != nil {
+		return "", errors.Wrapf(err, "in %q", path)
+	}
+	return filter, nil
+}
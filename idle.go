@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// isIdle returns whether the task has stayed started for longer than the
+// idle_after setting.
+func (tk task) isIdle(now time.Time) bool {
+	if cfg.IdleAfter <= 0 || tk.Status != "pending" {
+		return false
+	}
+	since, ok := parseStamp(tk.Start)
+	return ok && now.Sub(since) > time.Duration(cfg.IdleAfter)
+}
+
+// idleAsked holds the idle tasks already asked about this session, so that
+// keeping one running doesn't ask again on every action.
+var idleAsked = make(map[string]bool)
+
+// checkIdle asks whether to stop an idle task, stop it after the time
+// actually worked, or keep it running. It returns whether the task changed.
+func (t task) checkIdle() bool {
+	if !t.isIdle(time.Now()) || idleAsked[t.Uuid] || len(replaying) > 0 {
+		return false
+	}
+	idleAsked[t.Uuid] = true
+	since, _ := parseStamp(t.Start)
+	boldRed.Printf("Started %sago. Still on it?\n", age(time.Since(since)))
+	fmt.Print("s to stop it now, a to stop it after the time worked, any other key to keep it running. ")
+	r := readKey()
+	fmt.Println()
+	switch r {
+	case 's':
+		t.stopTask()
+		return true
+	case 'a':
+		return t.stopAfter()
+	}
+	return false
+}
+
+// stopAfter stops the task, ending the interval tracked on it in timewarrior
+// after the time worked, as typed in.
+func (t task) stopAfter() bool {
+	text := readLine("Worked for (say 1h30m): ")
+	if len(text) == 0 {
+		return false
+	}
+	worked, err := parseDuration(text)
+	if err != nil || worked <= 0 {
+		notice = fmt.Sprintf("Can't make out a duration from %q.", text)
+		return false
+	}
+	iv, ok := activeInterval()
+	if !ok || !iv.hasTag(t.Uuid) {
+		t.stopTask()
+		notice = "Stopped, with no time tracked on it to adjust."
+		return true
+	}
+	start, err := time.Parse(stamp, iv.Start)
+	if err != nil {
+		lg.Errorf("While parsing the start of the active interval %q: %v", iv.Start, err)
+		return false
+	}
+	end := start.Add(worked)
+	if end.After(time.Now()) {
+		notice = fmt.Sprintf("It's only been running for %s.", age(time.Since(start)))
+		return false
+	}
+	t.Start = ""
+	t.doImport()
+	timew("stop", end.UTC().Format(stamp))
+	forgetTracked()
+	return true
+}
+
+// trackPromptSettings control the offer to start tracking a task, when it's
+// opened with nothing being tracked.
+type trackPromptSettings struct {
+	// State is the color of the tasks to offer it for. Without one, it's off.
+	State string `json:"state,omitempty"`
+	// From and To are the working hours, as hours of the day, to offer it
+	// in. They default to 9 and 18.
+	From int `json:"from,omitempty"`
+	To   int `json:"to,omitempty"`
+	// Weekends offers it on Saturdays and Sundays too.
+	Weekends bool `json:"weekends,omitempty"`
+}
+
+// workingHours returns whether now is within the working hours.
+func (s trackPromptSettings) workingHours(now time.Time) bool {
+	from, to := s.From, s.To
+	if from == 0 && to == 0 {
+		from, to = 9, 18
+	}
+	if wd := now.Weekday(); !s.Weekends && (wd == time.Saturday || wd == time.Sunday) {
+		return false
+	}
+	return now.Hour() >= from && now.Hour() < to
+}
+
+// tracking returns whether any task is started, or timewarrior is tracking
+// anything at all.
+func tracking() bool {
+	for _, t := range db.tasks {
+		if len(t.Start) > 0 && t.Status == "pending" {
+			return true
+		}
+	}
+	_, ok := cachedInterval()
+	return ok
+}
+
+// trackAsked holds the tasks already offered tracking this session.
+var trackAsked = make(map[string]bool)
+
+// offerTracking offers to start the task, if it's in the track_prompt state,
+// it's working hours, and nothing is being tracked. It returns whether the
+// task changed.
+func (t task) offerTracking() bool {
+	s := cfg.TrackPrompt
+	if len(s.State) == 0 || t.colorTag() != s.State || t.Status != "pending" ||
+		len(t.Start) > 0 || trackAsked[t.Uuid] || len(replaying) > 0 ||
+		!s.workingHours(time.Now()) || tracking() {
+		return false
+	}
+	trackAsked[t.Uuid] = true
+	fmt.Printf("Nothing is being tracked. Start tracking this? [y/N] ")
+	r := readKey()
+	fmt.Println()
+	if r != 'y' && r != 'Y' {
+		return false
+	}
+	t.startTask()
+	return true
+}
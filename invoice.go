@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// billableTag marks the tasks whose tracked time is billed to their client.
+const billableTag = "billable"
+
+var (
+	invoiceFlags  = commandFlags("invoice", "")
+	invoiceFrom   = invoiceFlags.String("from", "", "First day to bill, as 2006-01-02. Defaults to the start of this month.")
+	invoiceTo     = invoiceFlags.String("to", "", "Last day to bill, as 2006-01-02. Defaults to today.")
+	invoiceClient = invoiceFlags.String("client", "", "Only bill this client.")
+	invoiceOut    = invoiceFlags.String("o", "", "File to write to, instead of stdout.")
+)
+
+func (t task) toggleBillable() int {
+	if t.hasTag(billableTag) {
+		t.Tags = remove(t.Tags, billableTag)
+	} else {
+		t.Tags = append(t.Tags, billableTag)
+	}
+	t.doImport()
+	return 0
+}
+
+// editClient sets the client of the task. Entering nothing removes it, and
+// Esc leaves it as it is.
+func (t task) editClient() int {
+	client, ok := editLineOk("Client: ")
+	if client = strings.Trim(client, " "); !ok || client == t.Client {
+		return 0
+	}
+	t.Client = client
+	t.doImport()
+	return 0
+}
+
+// billRange returns the range of time the invoice covers, from the start of
+// the first day to the end of the last.
+func billRange() (time.Time, time.Time, error) {
+	now := time.Now()
+	y, m, d := now.Date()
+	from := time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+	to := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	var err error
+	if len(*invoiceFrom) > 0 {
+		if from, err = time.ParseInLocation("2006-01-02", *invoiceFrom, now.Location()); err != nil {
+			return from, to, errors.Wrapf(err, "while parsing -from")
+		}
+	}
+	if len(*invoiceTo) > 0 {
+		if to, err = time.ParseInLocation("2006-01-02", *invoiceTo, now.Location()); err != nil {
+			return from, to, errors.Wrapf(err, "while parsing -to")
+		}
+	}
+	to = to.AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return from, to, errors.Errorf("-from %s is after -to %s", *invoiceFrom, *invoiceTo)
+	}
+	return from, to, nil
+}
+
+// billed is the time tracked for a client on a project.
+type billed struct {
+	client, project string
+	time            time.Duration
+}
+
+// runInvoice sums the time tracked on billable tasks over the range, per
+// client and project, into a CSV to invoice from.
+func runInvoice() error {
+	loadSettings()
+	from, to, err := billRange()
+	if err != nil {
+		return err
+	}
+	if err := db.load(); err != nil {
+		return err
+	}
+	ivs, err := exportIntervals("from", from.UTC().Format(stamp), "to", to.UTC().Format(stamp))
+	if err != nil {
+		return err
+	}
+
+	sums := make(map[[2]string]time.Duration)
+	byDesc := descriptions()
+	// Intervals still running are billed up to now.
+	until := to
+	if now := time.Now(); now.Before(until) {
+		until = now
+	}
+	for _, iv := range ivs {
+		tk, ok := iv.taskOf(byDesc)
+		if !ok || !tk.hasTag(billableTag) {
+			continue
+		}
+		client, project := tk.Client, tk.Project
+		if len(*invoiceClient) > 0 && client != *invoiceClient {
+			continue
+		}
+		if len(client) == 0 {
+			client = unassigned
+		}
+		if len(project) == 0 {
+			project = unassigned
+		}
+		sums[[2]string{client, project}] += iv.within(from, until)
+	}
+	var rows []billed
+	for k, dur := range sums {
+		if dur > 0 {
+			rows = append(rows, billed{k[0], k[1], dur})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].client != rows[j].client {
+			return rows[i].client < rows[j].client
+		}
+		return rows[i].project < rows[j].project
+	})
+
+	out, err := openOutput(*invoiceOut)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(out)
+	cw.Write([]string{"client", "project", "from", "to", "hours"})
+	first, last := from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02")
+	for _, r := range rows {
+		cw.Write([]string{r.client, r.project, first, last,
+			fmt.Sprintf("%.2f", r.time.Hours())})
+	}
+	cw.Flush()
+	err = cw.Error()
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/manishrjain/keys"
+)
+
+var bindingsPath = flag.String("bindings", os.Getenv("HOME")+"/.taskreview.bindings",
+	"Path of the shortcuts rebound on the keys screen, which win over the keys file.")
+
+// keyGroups are the shortcut groups, in the order the keys screen lists them.
+var keyGroups = []struct{ group, label string }{
+	{"help", "Shell"},
+	{"tasks", "Task list"},
+	{"task", "Task"},
+	{"project", "Projects"},
+	{"user", "Users"},
+	{"tag", "Tags"},
+	{"color", "Colors"},
+	{"dispute", "Dispute states"},
+	{"bulk", "Bulk actions"},
+}
+
+// keymap wraps the shortcuts from the keys package, with the keys rebound on
+// the keys screen layered on top.
+type keymap struct {
+	*keys.Shortcuts
+	// bound holds the rebound keys of each group, and what they map to.
+	bound map[string]map[rune]string
+	// names holds everything auto assigned in each group, including those
+	// which ran out of keys.
+	names map[string][]string
+	// picked is the last name picked by prefix, which pickedKey maps to.
+	picked struct{ group, name string }
+}
+
+func (k *keymap) AutoAssign(name, group string) {
+	for _, n := range k.names[group] {
+		if n == name {
+			k.Shortcuts.AutoAssign(name, group)
+			return
+		}
+	}
+	k.names[group] = append(k.names[group], name)
+	k.Shortcuts.AutoAssign(name, group)
+}
+
+type binding struct {
+	key  rune
+	name string
+}
+
+func newKeymap(s *keys.Shortcuts) *keymap {
+	k := &keymap{Shortcuts: s, bound: make(map[string]map[rune]string),
+		names: make(map[string][]string)}
+	data, err := ioutil.ReadFile(*bindingsPath)
+	if err != nil {
+		return k
+	}
+	// Stored as group -> name -> key, which reads better by hand.
+	var stored map[string]map[string]string
+	if err := json.Unmarshal(data, &stored); err != nil {
+		lg.Errorf("While parsing bindings %q: %v", *bindingsPath, err)
+		return k
+	}
+	for group, names := range stored {
+		for name, key := range names {
+			if r := []rune(key); len(r) == 1 {
+				k.bind(group, r[0], name)
+			}
+		}
+	}
+	return k
+}
+
+func (k *keymap) save() {
+	stored := make(map[string]map[string]string)
+	for group, keys := range k.bound {
+		stored[group] = make(map[string]string)
+		for r, name := range keys {
+			stored[group][name] = string(r)
+		}
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		lg.Errorf("While marshalling bindings: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(*bindingsPath, data, 0644); err != nil {
+		lg.Errorf("While saving bindings to %q: %v", *bindingsPath, err)
+	}
+}
+
+// bind maps r to name in the group, dropping any key name was rebound to.
+func (k *keymap) bind(group string, r rune, name string) {
+	m, ok := k.bound[group]
+	if !ok {
+		m = make(map[rune]string)
+		k.bound[group] = m
+	}
+	for br, bname := range m {
+		if bname == name {
+			delete(m, br)
+		}
+	}
+	m[r] = name
+}
+
+func (k *keymap) isRebound(group, name string) bool {
+	for _, bname := range k.bound[group] {
+		if bname == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *keymap) MapsTo(r rune, group string) (string, bool) {
+	if r == pickedKey && group == k.picked.group {
+		return k.picked.name, true
+	}
+	if name, ok := k.bound[group][r]; ok {
+		return name, true
+	}
+	name, ok := k.Shortcuts.MapsTo(r, group)
+	if ok && k.isRebound(group, name) {
+		return "", false
+	}
+	return name, ok
+}
+
+// table returns every mapping in the group, sorted by name. The keys package
+// can't list them, so this asks it about every printable key.
+func (k *keymap) table(group string) []binding {
+	var res []binding
+	for r := rune(33); r < 127; r++ {
+		if name, ok := k.MapsTo(r, group); ok {
+			res = append(res, binding{r, name})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].name < res[j].name })
+	return res
+}
+
+// keyFor returns the key the named shortcut of the group is on.
+func (k *keymap) keyFor(name, group string) (rune, bool) {
+	for _, b := range k.table(group) {
+		if b.name == name {
+			return b.key, true
+		}
+	}
+	return 0, false
+}
+
+// Print shows the shortcuts of the group. Groups without rebound keys are
+// left to the keys package.
+func (k *keymap) Print(group string, vertical bool) {
+	if len(k.bound[group]) == 0 {
+		k.Shortcuts.Print(group, vertical)
+		return
+	}
+	sep := "  "
+	if vertical {
+		sep = "\n"
+	}
+	for _, b := range k.table(group) {
+		color.New(color.BgWhite, color.FgBlack).Printf(" %c ", b.key)
+		fmt.Printf(" %s%s", b.name, sep)
+	}
+	if !vertical {
+		fmt.Println()
+	}
+}
+
+// rebindKeys is the keys screen, to pick a group and rebind its shortcuts.
+// A key already taken in the group can be swapped.
+func rebindKeys() {
+	for {
+		clear()
+		boldBlue.Println("Shortcut groups")
+		for i, g := range keyGroups {
+			fmt.Printf(" %d. %s\n", i+1, g.label)
+		}
+		fmt.Println()
+		in := readLine("Group to rebind (Enter to go back): ")
+		i, err := strconv.Atoi(in)
+		if err != nil || i < 1 || i > len(keyGroups) {
+			return
+		}
+		rebindGroup(keyGroups[i-1].group, keyGroups[i-1].label)
+	}
+}
+
+func rebindGroup(group, label string) {
+	for {
+		clear()
+		boldBlue.Println(label)
+		table := short.table(group)
+		for i, b := range table {
+			fmt.Printf(" %3d. ", i+1)
+			color.New(color.BgWhite, color.FgBlack).Printf(" %c ", b.key)
+			fmt.Printf(" %s\n", b.name)
+		}
+		fmt.Println()
+		in := readLine("Shortcut to rebind (Enter to go back): ")
+		i, err := strconv.Atoi(strings.TrimSpace(in))
+		if err != nil || i < 1 || i > len(table) {
+			return
+		}
+		b := table[i-1]
+		fmt.Printf("Press the new key for %q: ", b.name)
+		r := readKey()
+		fmt.Printf("%c\n", r)
+		if r <= 32 || r >= 127 {
+			continue
+		}
+		if other, ok := short.MapsTo(r, group); ok && other != b.name {
+			fmt.Printf("%c is taken by %q. Swap them? [y/N] ", r, other)
+			if c := readKey(); c != 'y' && c != 'Y' {
+				continue
+			}
+			short.bind(group, b.key, other)
+		}
+		short.bind(group, r, b.name)
+		short.save()
+	}
+}
+
+var lastKeysPath = flag.String("last-keys", os.Getenv("HOME")+"/.taskreview.lastkeys",
+	"Path to remember the shortcuts of the last session, to keep them stable.")
+
+// saveLast records every shortcut, for stabilize to compare against next time.
+func (k *keymap) saveLast() {
+	last := make(map[string]map[string]string)
+	for _, g := range keyGroups {
+		last[g.group] = make(map[string]string)
+		for _, b := range k.table(g.group) {
+			last[g.group][b.name] = string(b.key)
+		}
+	}
+	data, err := json.MarshalIndent(last, "", "  ")
+	if err != nil {
+		lg.Errorf("While marshalling shortcuts: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(*lastKeysPath, data, 0644); err != nil {
+		lg.Errorf("While saving shortcuts to %q: %v", *lastKeysPath, err)
+	}
+}
+
+// stabilize keeps shortcuts on the keys they had last session, so that new
+// projects, users or tags don't shuffle them around. Entries that moved are
+// pinned back, and a new entry which loses its key to that gets a free one.
+// It returns what it had to do, to report.
+func (k *keymap) stabilize() []string {
+	data, err := ioutil.ReadFile(*lastKeysPath)
+	if err != nil {
+		return nil
+	}
+	var last map[string]map[string]string
+	if err := json.Unmarshal(data, &last); err != nil {
+		lg.Errorf("While parsing shortcuts %q: %v", *lastKeysPath, err)
+		return nil
+	}
+	var changes []string
+	for _, g := range keyGroups {
+		before := make(map[string]bool)
+		for _, b := range k.table(g.group) {
+			before[b.name] = true
+		}
+		var pinned bool
+		for _, b := range k.table(g.group) {
+			r := []rune(last[g.group][b.name])
+			if len(r) != 1 || r[0] == b.key {
+				continue
+			}
+			if _, ok := k.bound[g.group][r[0]]; ok {
+				// Rebound on purpose, which wins.
+				continue
+			}
+			k.bind(g.group, r[0], b.name)
+			pinned = true
+		}
+		if !pinned {
+			continue
+		}
+		// Find whoever lost their key to a pinned one a free key.
+		have := make(map[string]bool)
+		for _, b := range k.table(g.group) {
+			have[b.name] = true
+		}
+		for name := range before {
+			if have[name] {
+				continue
+			}
+			if r, ok := k.freeKey(g.group, name); ok {
+				k.bind(g.group, r, name)
+				changes = append(changes, fmt.Sprintf("%s %q moved to %c, to keep the others stable.",
+					g.label, name, r))
+			} else {
+				changes = append(changes, fmt.Sprintf("%s %q has no key left.", g.label, name))
+			}
+		}
+	}
+	if len(changes) > 0 {
+		k.save()
+	}
+	return changes
+}
+
+// freeKey finds a key not taken in the group, trying the letters of the
+// name first.
+func (k *keymap) freeKey(group, name string) (rune, bool) {
+	candidates := strings.ToLower(name) + strings.ToUpper(name) +
+		"abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	for _, r := range candidates {
+		if r <= 32 || r >= 127 || r == leaderKey {
+			continue
+		}
+		if _, taken := k.MapsTo(r, group); !taken {
+			return r, true
+		}
+	}
+	return 0, false
+}
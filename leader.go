@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// leaderKey starts picking by name at the project, user and tag prompts,
+// where there are too many entries for memorable single keys.
+const leaderKey = '/'
+
+var leaderGroups = map[string]bool{"project": true, "user": true, "tag": true}
+
+// pickedKey is returned for a name picked by prefix, and maps to it in the
+// group it was picked from. That way, prompts handle it like any other key.
+const pickedKey = unicode.MaxRune
+
+// pickLabels pick among the names matching a prefix.
+const pickLabels = "123456789"
+
+// pickByPrefix narrows down the names in the group as a prefix is typed,
+// showing the matches after every key. A prefix matching just one name picks
+// it, as does Enter for the first match. Once the matches fit on one line,
+// they're numbered, and a number picks one, unless it continues the prefix
+// of a match. It returns pickedKey, or 0 if nothing got picked.
+func (k *keymap) pickByPrefix(group string) rune {
+	names := append([]string{}, k.names[group]...)
+	sort.Strings(names)
+	var prefix string
+	// Keys come a byte at a time, so hold on to the start of a multibyte
+	// rune until the rest of it comes in.
+	var pending []byte
+	for {
+		var matches []string
+		for _, n := range names {
+			if strings.HasPrefix(strings.ToLower(n), prefix) {
+				matches = append(matches, n)
+			}
+		}
+		if len(matches) == 1 && len(prefix) > 0 {
+			return k.pick(group, matches[0])
+		}
+		labeled := len(matches) <= len(pickLabels)
+		fmt.Printf("\r\033[K%c%s ", leaderKey, prefix)
+		for i, m := range matches {
+			if i >= len(pickLabels) {
+				fmt.Printf(" ... %d more", len(matches)-i)
+				break
+			}
+			if labeled {
+				fmt.Printf(" %c:%s", pickLabels[i], m)
+			} else {
+				fmt.Printf(" %s", m)
+			}
+		}
+		r := responseKey("")
+		switch {
+		case r == 10 && len(matches) > 0: // Enter
+			return k.pick(group, matches[0])
+		case r == 27 || r == 10: // Esc
+			fmt.Println()
+			return 0
+		case r == 127 || r == 8: // Backspace
+			if rs := []rune(prefix); len(pending) == 0 && len(rs) > 0 {
+				prefix = string(rs[:len(rs)-1])
+			}
+			pending = nil
+		case labeled && strings.ContainsRune(pickLabels, r) && !continues(matches, prefix+string(r)):
+			if i := strings.IndexRune(pickLabels, r); i < len(matches) {
+				return k.pick(group, matches[i])
+			}
+		case r > 0xff:
+			prefix += strings.ToLower(string(r))
+		default:
+			if pending = append(pending, byte(r)); utf8.FullRune(pending) {
+				prefix += strings.ToLower(string(pending))
+				pending = nil
+			}
+		}
+	}
+}
+
+// continues returns whether any of the names starts with the prefix.
+func continues(names []string, prefix string) bool {
+	for _, n := range names {
+		if strings.HasPrefix(strings.ToLower(n), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *keymap) pick(group, name string) rune {
+	fmt.Printf("\r\033[K%c%s\n", leaderKey, name)
+	k.picked.group, k.picked.name = group, name
+	return pickedKey
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+type badge struct {
+	letter string
+	icon   string
+	c      *color.Color
+	desc   string
+}
+
+// badges are the review badges shown at the start of each task line, in the
+// order they take precedence.
+var badges = []badge{
+	{"X", "✗", color.New(color.BgRed, color.FgWhite), "Deleted."},
+	{"D", "⚑", color.New(color.BgRed, color.FgWhite), "Disputed, waiting to be acknowledged."},
+	{"A", "⚐", color.New(color.BgYellow, color.FgBlack), "Dispute acknowledged, waiting to be resolved."},
+	{"R", "✔", color.New(color.BgGreen, color.FgBlack), "Reviewed by you."},
+	{"N", "●", color.New(color.BgBlue, color.FgWhite), "Not reviewed by you yet."},
+}
+
+// label returns the letter of the badge, or its symbol if icons are turned on.
+func (b badge) label() string {
+	if !cfg.Icons {
+		return b.letter
+	}
+	if s, ok := cfg.Symbols[b.letter]; ok {
+		return s
+	}
+	return b.icon
+}
+
+// statusBadge returns the review badge to show for the task.
+func statusBadge(tk task) badge {
+	switch {
+	case tk.Status == "deleted":
+		return badges[0]
+	case tk.disputeState() == kDisputed:
+		return badges[1]
+	case tk.disputeState() == kAcknowledged:
+		return badges[2]
+	case tk.isReviewed():
+		return badges[3]
+	}
+	return badges[4]
+}
+
+// showLegend explains what the states and badges mean, and lists all the
+// shortcuts.
+func showLegend() {
+	clear()
+	boldBlue.Println("States")
+	for _, s := range cfg.States {
+		stateColor(s.Name).Printf(" %-10s ", stateBadge(s.Name))
+		fmt.Printf(" %s\n", s.Description)
+	}
+	fmt.Println()
+
+	boldBlue.Println("Badges")
+	for _, b := range badges {
+		b.c.Printf(" %s ", b.label())
+		fmt.Printf(" %s\n", b.desc)
+	}
+	fmt.Println()
+
+	for _, g := range []struct{ label, group string }{
+		{"Shell", "help"},
+		{"Task list", "tasks"},
+		{"Task", "task"},
+		{"Dispute states", "dispute"},
+	} {
+		boldBlue.Println(g.label)
+		short.Print(g.group, true)
+		fmt.Println()
+	}
+	fmt.Println("Press any key to go back.")
+	readKey()
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// lineEditor edits a line of input a key at a time, for the prompts which
+// read text. Besides typing and Backspace, it takes Ctrl-U to clear the line,
+// Ctrl-W to delete a word, Ctrl-A and Ctrl-E to go to its start and end, the
+// left and right arrows to move, and the up and down arrows to recall lines
+// entered earlier at prompts of the same kind.
+type lineEditor struct {
+	line []rune
+	pos  int
+	// history holds the lines entered at prompts of this kind, oldest first.
+	history *[]string
+	// recall is how far back in history the line is, with the line being
+	// typed, kept in typed, at 0.
+	recall int
+	typed  []rune
+}
+
+// Results of lineEditor.key.
+const (
+	lineEditing = iota
+	lineEntered
+	lineCancelled
+)
+
+// histories are the lines entered this session, by the kind of prompt.
+var histories = make(map[string]*[]string)
+
+func newLineEditor(kind string) *lineEditor {
+	h, ok := histories[kind]
+	if !ok {
+		h = new([]string)
+		histories[kind] = h
+	}
+	return &lineEditor{history: h}
+}
+
+func (e *lineEditor) String() string { return string(e.line) }
+
+// key applies the key to the line. The rest of an escape sequence or a
+// multibyte character is read with more, which returns false once no more
+// keys come right away. Esc on its own cancels.
+func (e *lineEditor) key(r rune, more func() (rune, bool)) int {
+	switch {
+	case r == 10 || r == 13: // Enter
+		if s := e.String(); len(s) > 0 {
+			if h := *e.history; len(h) == 0 || h[len(h)-1] != s {
+				*e.history = append(h, s)
+			}
+		}
+		return lineEntered
+	case r == 27: // Esc
+		next, ok := more()
+		if !ok {
+			return lineCancelled
+		}
+		if next != '[' && next != 'O' {
+			return lineEditing
+		}
+		code, ok := more()
+		if ok && code >= '0' && code <= '9' {
+			// Keys like Delete, which end in a ~, aren't handled.
+			more()
+		} else if ok {
+			e.arrow(code)
+		}
+	case r == 127 || r == 8: // Backspace
+		if e.pos > 0 {
+			e.line = append(e.line[:e.pos-1], e.line[e.pos:]...)
+			e.pos--
+		}
+	case r == 21: // Ctrl-U
+		e.line, e.pos = e.line[e.pos:], 0
+	case r == 23: // Ctrl-W
+		start := e.pos
+		for start > 0 && e.line[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && e.line[start-1] != ' ' {
+			start--
+		}
+		e.line = append(e.line[:start], e.line[e.pos:]...)
+		e.pos = start
+	case r == 1: // Ctrl-A
+		e.pos = 0
+	case r == 5: // Ctrl-E
+		e.pos = len(e.line)
+	case r < 32:
+		// Other control keys do nothing.
+	default:
+		if r >= utf8.RuneSelf {
+			r = e.decode(r, more)
+		}
+		e.line = append(e.line[:e.pos], append([]rune{r}, e.line[e.pos:]...)...)
+		e.pos++
+	}
+	return lineEditing
+}
+
+// arrow handles the final byte of an arrow key's escape sequence.
+func (e *lineEditor) arrow(code rune) {
+	switch code {
+	case 'D': // Left
+		if e.pos > 0 {
+			e.pos--
+		}
+	case 'C': // Right
+		if e.pos < len(e.line) {
+			e.pos++
+		}
+	case 'A': // Up
+		if e.recall < len(*e.history) {
+			if e.recall == 0 {
+				e.typed = e.line
+			}
+			e.recall++
+			e.setLine([]rune((*e.history)[len(*e.history)-e.recall]))
+		}
+	case 'B': // Down
+		if e.recall > 0 {
+			e.recall--
+			if e.recall == 0 {
+				e.setLine(e.typed)
+			} else {
+				e.setLine([]rune((*e.history)[len(*e.history)-e.recall]))
+			}
+		}
+	}
+}
+
+func (e *lineEditor) setLine(line []rune) {
+	e.line = append([]rune{}, line...)
+	e.pos = len(e.line)
+}
+
+// decode reads the rest of the UTF-8 character which starts with the byte b.
+func (e *lineEditor) decode(b rune, more func() (rune, bool)) rune {
+	buf := []byte{byte(b)}
+	for !utf8.FullRune(buf) {
+		next, ok := more()
+		if !ok {
+			break
+		}
+		buf = append(buf, byte(next))
+	}
+	r, _ := utf8.DecodeRune(buf)
+	return r
+}
+
+// render redraws the prompt and the line, leaving the cursor where it is in
+// the line.
+func (e *lineEditor) render(prompt string) {
+	fmt.Printf("\r\033[K%s%s", prompt, e.String())
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Printf("\033[%dD", back)
+	}
+}
+
+// printCursor prints the line with the cursor shown on it, for screens which
+// redraw everything and so can't leave the terminal's cursor there.
+func (e *lineEditor) printCursor() {
+	cursor := color.New(color.ReverseVideo)
+	fmt.Print(string(e.line[:e.pos]))
+	if e.pos < len(e.line) {
+		cursor.Print(string(e.line[e.pos]))
+		fmt.Print(string(e.line[e.pos+1:]))
+	} else {
+		cursor.Print(" ")
+	}
+}
+
+// editLine shows the prompt and reads back a line, edited with lineEditor.
+// History is kept by prompt. Esc returns an empty line.
+func editLine(prompt string) string {
+	line, _ := editLineOk(prompt)
+	return line
+}
+
+// editLineOk is editLine, returning false if Esc cancelled it.
+func editLineOk(prompt string) (string, bool) {
+	singleCharMode()
+	e := newLineEditor(prompt)
+	more := func() (rune, bool) { return readKeyTimeout(100 * time.Millisecond) }
+	b := make([]byte, 1)
+	for {
+		e.render(prompt)
+		if _, err := os.Stdin.Read(b); err != nil {
+			lg.Fatalf("While reading %q: %v", prompt, err)
+		}
+		switch e.key(rune(b[0]), more) {
+		case lineEntered:
+			fmt.Println()
+			return e.String(), true
+		case lineCancelled:
+			fmt.Println()
+			return "", false
+		}
+	}
+}
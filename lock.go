@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	lockPath = flag.String("lock", os.Getenv("HOME")+"/.taskreview.lock",
+		"Lock file which keeps two sessions from clobbering each other's imports.")
+	force = flag.Bool("force", false,
+		"Run even if another live session holds the lock.")
+)
+
+// lockHolder returns the pid in the lock file, and whether it's still
+// running.
+func lockHolder() (int, bool) {
+	data, err := ioutil.ReadFile(*lockPath)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, false
+	}
+	// Signal 0 only checks whether the process exists.
+	err = syscall.Kill(pid, 0)
+	return pid, err == nil || err == syscall.EPERM
+}
+
+// acquireLock takes the session lock, writing our pid into it. Locks left
+// behind by sessions which are no longer running are taken over.
+func acquireLock() error {
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(*lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return err
+		}
+		if !os.IsExist(err) {
+			return errors.Wrapf(err, "while creating lock %q", *lockPath)
+		}
+
+		pid, alive := lockHolder()
+		if alive && pid != os.Getpid() {
+			if !*force {
+				return errors.Errorf("another session (pid %d) holds %q."+
+					" Quit it, or run with -force.", pid, *lockPath)
+			}
+			lg.Errorf("Ignoring lock held by pid %d, due to -force.", pid)
+		} else {
+			lg.Infof("Taking over stale lock %q from pid %d.", *lockPath, pid)
+		}
+		if err := os.Remove(*lockPath); err != nil {
+			return errors.Wrapf(err, "while removing lock %q", *lockPath)
+		}
+	}
+	return errors.Errorf("unable to take lock %q", *lockPath)
+}
+
+// releaseLock removes the lock, if we still hold it.
+func releaseLock() {
+	if pid, _ := lockHolder(); pid != os.Getpid() {
+		return
+	}
+	if err := os.Remove(*lockPath); err != nil {
+		lg.Errorf("While removing lock %q: %v", *lockPath, err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+type logLevel int
+
+const (
+	levelError logLevel = iota
+	levelInfo
+	levelDebug
+)
+
+// maxLogOutput caps how much of a subprocess's output gets logged, so that
+// a full export doesn't blow up the log file.
+const maxLogOutput = 4096
+
+var (
+	verbose = flag.Bool("verbose", false, "Log informational messages.")
+	debug   = flag.Bool("debug", false,
+		"Log every task invocation, with its arguments, duration and output.")
+	logPath = flag.String("log", os.Getenv("HOME")+"/.taskreview.log",
+		"Path of the log file.")
+	lg = &logger{level: levelError, out: log.New(os.Stderr, "", log.LstdFlags)}
+)
+
+// logger writes leveled messages to the log file. The interactive screen is
+// left alone, except when we're about to exit on a fatal error.
+type logger struct {
+	level logLevel
+	out   *log.Logger
+	file  *os.File
+}
+
+// initLogger opens the log file and picks the level based on the flags. It
+// must be called after flag.Parse.
+func initLogger() {
+	switch {
+	case *debug:
+		lg.level = levelDebug
+	case *verbose:
+		lg.level = levelInfo
+	}
+	f, err := os.OpenFile(*logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		lg.Errorf("Unable to open log file %q: %v. Logging to stderr.", *logPath, err)
+		return
+	}
+	lg.file = f
+	lg.out = log.New(f, "", log.LstdFlags|log.Lmicroseconds)
+}
+
+func (l *logger) logf(lvl logLevel, prefix, format string, args ...interface{}) {
+	if lvl > l.level {
+		return
+	}
+	l.out.Output(3, prefix+fmt.Sprintf(format, args...))
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	l.logf(levelDebug, "DEBUG ", format, args...)
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	l.logf(levelInfo, "INFO  ", format, args...)
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	l.logf(levelError, "ERROR ", format, args...)
+}
+
+// Fatalf logs the error, restores the terminal and exits.
+func (l *logger) Fatalf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.logf(levelError, "FATAL ", "%s", msg)
+	lineInputMode()
+	fmt.Fprintln(os.Stderr, msg)
+	l.Close()
+	os.Exit(exitError)
+}
+
+func (l *logger) Close() {
+	if l.file != nil {
+		l.file.Close()
+	}
+}
+
+func truncated(b []byte) []byte {
+	if len(b) <= maxLogOutput {
+		return b
+	}
+	return append(b[:maxLogOutput:maxLogOutput], "...(truncated)"...)
+}
+
+// runCmd runs cmd, capturing and returning its stdout. The invocation, its
+// duration and output are logged at debug level.
+func runCmd(cmd *exec.Cmd) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	// Time by the task command, which comes last.
+	timed(filepath.Base(cmd.Args[0])+" "+cmd.Args[len(cmd.Args)-1], start)
+	lg.Debugf("Ran %q in %v. err: %v\nstdout: %s\nstderr: %s", cmd.Args,
+		time.Since(start), err, truncated(stdout.Bytes()), truncated(stderr.Bytes()))
+	return stdout.Bytes(), err
+}
+
+// runTask runs the task binary with the given arguments.
+func runTask(args ...string) ([]byte, error) {
+	return runCmd(exec.Command("task", args...))
+}
+
+// runTaskStream runs the task binary with the given arguments, handing its
+// output to read as it's produced, rather than buffering all of it.
+func runTaskStream(read func(r io.Reader) error, args ...string) error {
+	cmd := exec.Command("task", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	rerr := read(stdout)
+	// Drain whatever wasn't read, so that task can exit.
+	io.Copy(ioutil.Discard, stdout)
+	err = cmd.Wait()
+	timed("task "+args[len(args)-1], start)
+	lg.Debugf("Ran %q in %v. err: %v, read err: %v\nstderr: %s", cmd.Args,
+		time.Since(start), err, rerr, truncated(stderr.Bytes()))
+	if err != nil {
+		return err
+	}
+	return rerr
+}
+
+// runTaskInput runs the task binary with the given arguments, feeding it
+// input over stdin. No shell is involved, so the input needs no quoting.
+func runTaskInput(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("task", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	return runCmd(cmd)
+}
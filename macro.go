@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// macro chains task actions under one key of the task view. Each step is an
+// action, as named on the task view, optionally followed by what to pick at
+// its prompt:
+//
+//	color red      pick the red color
+//	assigned me    assign to yourself, the reviewer
+//	tags urgent    toggle the urgent tag
+//	reviewed       actions without a prompt take nothing
+//
+// A step without a pick prompts as usual.
+type macro struct {
+	Name  string   `json:"name"`
+	Key   string   `json:"key,omitempty"`
+	Steps []string `json:"steps"`
+}
+
+// macroPicks are the names still to pick at the prompts of the running
+// macro step, in place of reading keys.
+var macroPicks []string
+
+func findMacro(name string) (macro, bool) {
+	for _, m := range cfg.Macros {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return macro{}, false
+}
+
+// assignMacros gives the macros their keys in the task view, and checks
+// their steps only use actions it has.
+func assignMacros() {
+	actions := make(map[string]bool)
+	for _, b := range short.table("task") {
+		actions[b.name] = true
+	}
+	// Macros can't repeat, or run other macros.
+	delete(actions, "repeat")
+	for _, m := range cfg.Macros {
+		delete(actions, m.Name)
+	}
+	for _, m := range cfg.Macros {
+		for _, step := range m.Steps {
+			if action, _ := splitStep(step); !actions[action] {
+				lg.Fatalf("Macro %q in %q has a step with an unknown action: %q",
+					m.Name, *settingsPath, step)
+			}
+		}
+		if len(m.Key) > 0 {
+			short.BestEffortAssign(rune(m.Key[0]), m.Name, "task")
+		} else {
+			short.AutoAssign(m.Name, "task")
+		}
+	}
+}
+
+// splitStep returns the action of the step, and what to pick at its prompt.
+// Action names can have spaces, so the pick is whatever follows the longest
+// known action.
+func splitStep(step string) (string, string) {
+	step = strings.TrimSpace(step)
+	var action string
+	for _, b := range short.table("task") {
+		if len(b.name) <= len(action) {
+			continue
+		}
+		if step == b.name || strings.HasPrefix(step, b.name+" ") {
+			action = b.name
+		}
+	}
+	if len(action) == 0 {
+		return step, ""
+	}
+	return action, strings.TrimSpace(step[len(action):])
+}
+
+// runMacro runs the steps of the macro on the task at idx, in order. It stops
+// at a step which was meant to pick something, but didn't change the task.
+// It returns the move of the last step run, as steps all work on the same
+// task.
+func runMacro(m macro, tasks []task, idx int) int {
+	var move int
+	for _, step := range m.Steps {
+		action, pick := splitStep(step)
+		if pick == "me" {
+			pick = reviewerName(*reviewTag)
+		}
+		macroPicks = nil
+		if len(pick) > 0 {
+			macroPicks = []string{pick}
+		}
+		before := imports
+		move = taskAction(action, tasks, idx)
+		macroPicks = nil
+		// Have the next step work on the task as this one left it.
+		tasks[idx] = cachedTask(tasks[idx].Uuid)
+		if len(pick) > 0 && imports == before {
+			notice = fmt.Sprintf("Macro %q stopped at %q.", m.Name, step)
+			return 0
+		}
+		if move == quitReview {
+			break
+		}
+	}
+	return move
+}
+
+// macroPick picks the next of macroPicks at the prompt for the group. It
+// returns pickedKey, or Esc if the group has no such name.
+func (k *keymap) macroPick(group string) rune {
+	name := macroPicks[0]
+	macroPicks = macroPicks[1:]
+	known := false
+	for _, b := range k.table(group) {
+		known = known || b.name == name
+	}
+	for _, n := range k.names[group] {
+		known = known || n == name
+	}
+	if !known {
+		fmt.Printf("No %q to pick.\n", name)
+		return 27
+	}
+	fmt.Println(name)
+	k.picked.group, k.picked.name = group, name
+	return pickedKey
+}
@@ -1,22 +1,18 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"os/exec"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/manishrjain/keys"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -25,34 +21,89 @@ const (
 	URGENCY = iota
 	DATE
 	COLOR
+	RANDOM
 )
 
 var (
-	uuidExp   *regexp.Regexp
-	boldGreen *color.Color
-	boldRed   *color.Color
-	boldBlue  *color.Color
-	config    = flag.String("config", os.Getenv("HOME")+"/.taskreview",
+	uuidExp    *regexp.Regexp
+	mentionExp *regexp.Regexp
+	knownUsers = make(map[string]bool)
+	boldGreen  *color.Color
+	boldRed    *color.Color
+	boldBlue   *color.Color
+	config     = flag.String("config", os.Getenv("HOME")+"/.taskreview",
 		"Config path for key persistence.")
 	reviewTag = flag.String("rtag", "r:"+os.Getenv("USER"),
 		"Tag to use for marking tasks as reviewed.")
-	cmdfilter = flag.String("f", "", "Filter specified in commandline.")
-	short     *keys.Shortcuts
-	showAll   bool
-	sortBy    = URGENCY
+	reviewers = flag.String("reviewers", "",
+		"Comma separated review tags of everyone who signs off on tasks,"+
+			" for e.g. r:alice,r:bob. Defaults to just rtag.")
+	reviewWindow = flag.Duration("review-window", 24*time.Hour,
+		"How long a review of a pending task lasts, before it needs another.")
+	completedReviewWindow = flag.Duration("completed-review-window", 0,
+		"How long a review of a completed task lasts. Zero means forever.")
+	cmdfilter      = flag.String("f", "", "Filter specified in commandline.")
+	confirmActions = flag.Bool("confirm", false,
+		"Ask for confirmation before deleting a task, or marking it done.")
+	undoWindow = flag.Duration("undo-window", 3*time.Second,
+		"How long to offer undo after deleting a task, or marking it done.")
+	wrap = flag.Bool("wrap", false,
+		"Wrap around at the ends of the list, instead of leaving the review.")
+	dryRun = flag.Bool("dry-run", false,
+		"Show what every change would import, without importing it.")
+	resetOnDelegate = flag.Bool("reset-on-delegate", true,
+		"Reset the review state of delegated tasks, so the new owner reviews them.")
+	short   *keymap
+	showAll bool
+	sortBy  = URGENCY
 )
 
 func init() {
 	var err error
 	uuidExp, err = regexp.Compile("([0-9a-f]{8})")
 	if err != nil {
-		log.Fatal(err)
+		lg.Fatalf("While compiling uuid regexp: %v", err)
+	}
+	mentionExp, err = regexp.Compile(`@([\w.-]+)`)
+	if err != nil {
+		lg.Fatalf("While compiling mention regexp: %v", err)
 	}
 	boldGreen = color.New(color.FgGreen).Add(color.Bold)
 	boldRed = color.New(color.FgRed).Add(color.Bold)
 	boldBlue = color.New(color.FgBlue).Add(color.Bold)
 }
 
+// trackedReviewers returns the review tags whose sign offs are shown.
+func trackedReviewers() []string {
+	if len(*reviewers) == 0 {
+		return []string{*reviewTag}
+	}
+	var res []string
+	for _, r := range strings.Split(*reviewers, ",") {
+		if r = strings.TrimSpace(r); len(r) > 0 {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// showTime formats a timestamp for display, either as an absolute date or,
+// with the relative_times setting, as how long ago it was.
+func showTime(t time.Time) string {
+	if cfg.RelativeTimes {
+		return age(time.Now().UTC().Sub(t)) + "ago"
+	}
+	return t.Format(format)
+}
+
+// reviewerName strips the review tag down to the reviewer's name.
+func reviewerName(rtag string) string {
+	if idx := strings.Index(rtag, ":"); idx >= 0 {
+		return rtag[idx+1:]
+	}
+	return rtag
+}
+
 func age(dur time.Duration) string {
 	var res string
 	if dur > 24*time.Hour {
@@ -71,62 +122,38 @@ func age(dur time.Duration) string {
 	return res
 }
 
-func getTask(uuid string) task {
-	cmd := exec.Command("task", uuid, "export")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
+// exportTask fetches the task with the given uuid, whatever its status.
+func exportTask(uuid string) (task, error) {
+	out, err := runTask(uuid, "export")
 	if err != nil {
-		log.Fatal(err)
+		return task{}, errors.Wrapf(err, "while exporting task %v", uuid)
 	}
 
 	var tasks []task
-	if err := json.Unmarshal(out.Bytes(), &tasks); err != nil {
-		log.Fatal(err)
+	start := time.Now()
+	if err := json.Unmarshal(out, &tasks); err != nil {
+		return task{}, errors.Wrapf(err, "while parsing task %v", uuid)
 	}
+	timed("decode", start)
 	if len(tasks) != 1 {
-		log.Fatalf("Expected exactly one task for: %v", uuid)
+		return task{}, errors.Errorf("expected exactly one task for: %v", uuid)
 	}
-	task := tasks[0]
-	return task
+	remember(tasks)
+	db.put(tasks[0])
+	return tasks[0], nil
 }
 
-func printSummary(tk task, idx, total int) {
-	pomo := color.New(color.BgBlack, color.FgWhite).PrintfFunc()
-
-	ptag := tk.colorTag()
-	user := tk.userTag()
-
-	switch ptag {
-	case "green":
-		pomo = color.New(color.BgGreen, color.FgBlack).PrintfFunc()
-	case "red":
-		pomo = color.New(color.BgRed, color.FgWhite).PrintfFunc()
-	case "blue":
-		pomo = color.New(color.BgBlue, color.FgWhite).PrintfFunc()
-	default:
-		// pass
+func getTask(uuid string) task {
+	tk, err := exportTask(uuid)
+	if err != nil {
+		lg.Fatalf("%v", err)
 	}
+	return tk
+}
 
+func printSummary(tk task, idx, total int) {
 	color.New(color.BgRed, color.FgWhite).Printf(" [%2d of %2d] ", idx, total)
-	if tk.Status == "deleted" {
-		color.New(color.BgRed, color.FgWhite).Printf(" X ")
-	} else if tk.isDisputed() {
-		color.New(color.BgRed, color.FgWhite).Printf(" D ")
-	} else if tk.isReviewed() {
-		color.New(color.BgGreen, color.FgBlack).Printf(" R ")
-	} else {
-		color.New(color.BgBlue, color.FgWhite).Printf(" N ")
-	}
-	color.New(color.BgYellow, color.FgBlack).Printf(" %13s ", user)
-	color.New(color.BgCyan).Printf(" %12s ", tk.Project)
-
-	desc := tk.Description
-	if len(desc) > 60 {
-		desc = desc[:60]
-	}
-	color.New(color.BgWhite, color.FgBlack).Printf(" %-60s", desc)
-	pomo(" %-10v ", ptag)
+	printColumns(tk)
 	fmt.Println()
 }
 
@@ -140,32 +167,38 @@ func isNormalTag(t string) bool {
 	if t[0] == '@' || t[0] == '-' {
 		return false
 	}
-	if t == "red" || t == "green" || t == "blue" {
+	if isStateTag(t) {
 		return false
 	}
 	return true
 }
 
 // Returns back how much to move the index by.
-func printInfo(tk task, idx, total int) int {
+func printInfo(tasks []task, idx int) int {
+	tk, total := tasks[idx], len(tasks)
+	start := time.Now()
 	clear()
+	checkPomodoro()
+	if len(notice) > 0 {
+		color.New(color.BgYellow, color.FgBlack).Printf(" %s ", notice)
+		fmt.Println()
+		notice = ""
+	}
+	printBanner("task")
+	printProgress(tasks)
 	fmt.Println()
 	printSummary(tk, idx, total)
 
-	started, err := time.Parse(stamp, tk.Created)
-	if err != nil {
-		log.Fatal(err)
-	}
-	finished := time.Now()
+	started, startOk := tk.entered()
+	finished, finishOk := time.Now(), true
 	if len(tk.Completed) > 0 {
-		finished, err = time.Parse(stamp, tk.Completed)
-		if err != nil {
-			log.Fatal(err)
-		}
+		finished, finishOk = tk.ended()
 	}
 	fmt.Println()
-	if len(tk.Description) > 60 {
-		fmt.Printf("Description:  %s\n", tk.Description)
+	// Show the description in full, if the list cuts it short.
+	if w, ok := columnWidth("description"); !ok || len(tk.Description) > w ||
+		strings.Contains(tk.Description, "\n") {
+		fmt.Printf("Description:  %s\n", wrapText(tk.Description, termWidth(), 14))
 	}
 	fmt.Printf("Tags:        ")
 	ntags := make([]string, 0, 10)
@@ -178,30 +211,215 @@ func printInfo(tk task, idx, total int) int {
 		color.New(color.FgRed+color.Attribute(i)).Printf(" %s", t)
 	}
 	fmt.Println()
-	fmt.Printf("Started:      %s\n", started.Format(format))
+	if startOk {
+		fmt.Printf("Started:      %s\n", showTime(started))
+	} else {
+		boldRed.Printf("Started:      unknown (%q)\n", tk.Created)
+	}
 	if len(tk.Completed) > 0 {
-		now := time.Now().UTC()
-		fmt.Printf("Completed:    %s [%vago]\n", finished.Format(format), age(now.Sub(finished)))
+		switch {
+		case finishOk && cfg.RelativeTimes:
+			fmt.Printf("Completed:    %s\n", showTime(finished))
+		case finishOk:
+			fmt.Printf("Completed:    %s [%vago]\n", showTime(finished), age(time.Now().UTC().Sub(finished)))
+		default:
+			boldRed.Printf("Completed:    unknown (%q)\n", tk.Completed)
+		}
+	}
+	if modified, ok := parseStamp(tk.Modified); ok {
+		fmt.Printf("Modified:     %s\n", showTime(modified))
+	}
+	if len(tk.Client) > 0 || tk.hasTag(billableTag) {
+		client := tk.Client
+		if len(client) == 0 {
+			client = unassigned
+		}
+		if tk.hasTag(billableTag) {
+			client += " [billable]"
+		}
+		fmt.Printf("Client:       %s\n", client)
+	}
+	if since, ok := parseStamp(tk.Start); ok {
+		boldGreen.Printf("Tracking:     since %s [%v]\n", showTime(since), age(time.Since(since)))
+	}
+	if due, ok := parseStamp(tk.Due); ok {
+		label, overdue := tk.dueLabel(time.Now())
+		if overdue {
+			boldRed.Printf("Due:          %s [%s]\n", due.Format(format), label)
+		} else {
+			fmt.Printf("Due:          %s [%s]\n", due.Format(format), label)
+		}
+	}
+	if startOk && finishOk {
+		fmt.Printf("Age:          %v\n", age(finished.Sub(started)))
+	} else {
+		boldRed.Printf("Age:          unknown\n")
+	}
+	tracked, trackedOk := tk.trackedTime()
+	if trackedOk {
+		fmt.Printf("Tracked:      %v\n", age(tracked))
+	}
+	if estimate, ok := tk.estimated(); ok {
+		switch {
+		case !trackedOk:
+			fmt.Printf("Estimate:     %v\n", age(estimate))
+		case tracked > estimate:
+			boldRed.Printf("Estimate:     %v [%s spent]\n", age(estimate), accuracy(estimate, tracked))
+		default:
+			fmt.Printf("Estimate:     %v [%s spent]\n", age(estimate), accuracy(estimate, tracked))
+		}
+	}
+	if tk.Pomodoros > 0 {
+		fmt.Printf("Pomodoros:    %d\n", tk.Pomodoros)
+	}
+	if n := len(tk.Annotations); n > 0 {
+		a := tk.Annotations[n-1]
+		var when string
+		if ts, ok := parseStamp(a.Entry); ok {
+			when = ts.Format(format) + ": "
+		}
+		fmt.Printf("Latest note:  %s%s [%d notes]\n", when, a.Description, n)
+	}
+	fmt.Printf("Urgency:      %.2f\n", tk.Urgency)
+	if tk.Id > 0 {
+		fmt.Printf("ID:           %d\n", tk.Id)
 	}
-	fmt.Printf("Age:          %v\n", age(finished.Sub(started)))
 	fmt.Printf("UUID:         %s\n", tk.Uuid)
 	fmt.Printf("XID:          %s\n", tk.Xid)
+	printDepends(tk, tasks)
+	if hist := tk.colorHistory(); len(hist) > 0 {
+		fmt.Println()
+		boldBlue.Println("Color history:")
+		for i, a := range hist {
+			var since string
+			if ts, err := time.Parse(stamp, a.Entry); err == nil {
+				// How long the task stayed in this color.
+				till := time.Now().UTC()
+				if i+1 < len(hist) {
+					if next, err := time.Parse(stamp, hist[i+1].Entry); err == nil {
+						till = next
+					}
+				}
+				since = fmt.Sprintf("%s  for %s", ts.Format(format), age(till.Sub(ts)))
+			}
+			fmt.Printf("  %-36s  %s\n", since, strings.TrimPrefix(a.Description, colorPrefix))
+		}
+	}
+	if thread := tk.disputeThread(); len(thread) > 0 {
+		fmt.Println()
+		boldRed.Println("Dispute:")
+		for _, a := range thread {
+			var when string
+			if ts, err := time.Parse(stamp, a.Entry); err == nil {
+				when = ts.Format(format)
+			}
+			fmt.Printf("  %s  %s\n", when, strings.TrimPrefix(a.Description, disputePrefix))
+		}
+	}
+	if done, pending := tk.signoffs(); len(done)+len(pending) > 1 {
+		fmt.Printf("Reviewers:   ")
+		for _, r := range done {
+			boldGreen.Printf(" %s", reviewerName(r))
+		}
+		for _, r := range pending {
+			boldRed.Printf(" %s", reviewerName(r))
+		}
+		fmt.Println()
+	}
 	fmt.Println()
+	if tk.checkIdle() || tk.offerTracking() {
+		return 0
+	}
 
 	short.Print("task", true)
-	r := make([]byte, 1)
-	os.Stdin.Read(r)
+	timed("render task", start)
+	r := contextKey("task")
+
+	ins, _ := short.MapsTo(r, "task")
+	if ins == "repeat" {
+		if len(lastAction.name) == 0 {
+			return 0
+		}
+		ins = lastAction.name
+		replaying = append([]rune{}, lastAction.keys...)
+	}
+	recording = nil
+	before := imports
+	var move int
+	if m, ok := findMacro(ins); ok {
+		move = runMacro(m, tasks, idx)
+	} else {
+		move = taskAction(ins, tasks, idx)
+	}
+	replaying = nil
+	if imports > before {
+		lastAction.name, lastAction.keys = ins, recording
+		if m, ok := cfg.Advance[ins]; ok {
+			// The action changed the task, so move as configured.
+			move = m
+		}
+	}
+	return move
+}
+
+// lastAction is the last task action which changed a task, along with the
+// keys picked at its prompts, so that it can be repeated.
+var lastAction struct {
+	name string
+	keys []rune
+}
+
+// recording holds the keys picked at prompts during the current action,
+// and replaying the keys to pick instead of reading them, when repeating.
+var recording, replaying []rune
 
-	ins, _ := short.MapsTo(rune(r[0]), "task")
+// taskAction runs the action on the task at idx. It returns how much to
+// move the index by.
+func taskAction(ins string, tasks []task, idx int) int {
+	tk := tasks[idx]
 	switch ins {
 	case "back":
 		return -1
 	case "quit":
-		return total
+		return quitReview
 	case "description":
 		return tk.editDescription()
 	case "assigned":
 		return tk.editAssigned()
+	case "delegate":
+		return tk.delegate()
+	case "pomodoro":
+		return tk.startPomodoro()
+	case "start":
+		return tk.startTask()
+	case "stop":
+		return tk.stopTask()
+	case "client":
+		return tk.editClient()
+	case "billable":
+		return tk.toggleBillable()
+	case "bookmark":
+		bookmarks[tk.Uuid] = !bookmarks[tk.Uuid]
+		return 0
+	case "next bookmark":
+		return nextMatching(tasks, idx, func(t task) bool { return bookmarks[t.Uuid] })
+	case "breadcrumb back":
+		return popBreadcrumb(tasks, idx)
+	case "blocker":
+		return jumpToBlocker(tasks, idx)
+	case "jump to active":
+		return jumpToActive(tasks, idx)
+	case "stop active":
+		stopActivity()
+		return 0
+	case "next unreviewed":
+		return nextMatching(tasks, idx, func(t task) bool { return !t.isReviewed() })
+	case "next disputed":
+		return nextMatching(tasks, idx, func(t task) bool { return t.isDisputed() })
+	case "add pomodoro":
+		return tk.addPomodoro()
+	case "retro note":
+		return tk.addRetroNote()
 	case "project":
 		return tk.editProject()
 	case "color":
@@ -214,95 +432,164 @@ func printInfo(tk task, idx, total int) int {
 		return tk.deleteTask()
 	case "done":
 		return tk.toggleDone()
+	case "reopen":
+		return tk.reopen()
 	case "disputed":
 		return tk.toggleDisputed()
+	case "reply to dispute":
+		return tk.replyToDispute()
+	case "acknowledge dispute":
+		return tk.acknowledgeDispute()
+	case "resolve dispute":
+		return tk.resolveDispute()
 	default:
 		return 1
 	}
 }
 
-func showAndGetResponse(header, label string) rune {
-	if len(header) > 0 {
-		color.New(color.BgRed, color.FgWhite).Printf(" %s: ", header)
-	}
-	short.Print(label, false)
+// readKey blocks until a single key is pressed.
+func readKey() rune {
 	r := make([]byte, 1)
 	os.Stdin.Read(r)
 	return rune(r[0])
 }
 
-func getTasks(filter string) ([]task, error) {
-	var cmd *exec.Cmd
-	var completed int
-	if len(filter) > 0 {
-		args := strings.Split(filter, " ")
-		args = append(args, "export")
-		argf := args[:0]
-		for _, arg := range args {
-			if arg == "_end" {
-				completed++
-				continue
-			}
-			argf = append(argf, arg)
+func showAndGetResponse(header, label string) rune {
+	if len(header) > 0 {
+		color.New(color.BgRed, color.FgWhite).Printf(" %s: ", header)
+	}
+	if len(macroPicks) > 0 {
+		return short.macroPick(label)
+	}
+	if len(replaying) == 0 {
+		short.Print(label, false)
+		if leaderGroups[label] {
+			fmt.Printf("%c to pick by name. ", leaderKey)
 		}
-		cmd = exec.Command("task", argf...)
-	} else {
-		cmd = exec.Command("task", "export")
 	}
+	r := responseKey(label)
+	if r == leaderKey && leaderGroups[label] {
+		return short.pickByPrefix(label)
+	}
+	return r
+}
+
+// responseKey reads a key at the prompt for the group. Keys are recorded, so
+// the action can be repeated, and come from the recording when it is. Ctrl-C
+// comes back as Esc, which no shortcut maps, so both cancel the prompt.
+func responseKey(group string) rune {
+	if len(replaying) > 0 {
+		r := replaying[0]
+		replaying = replaying[1:]
+		recording = append(recording, r)
+		return r
+	}
+	catchInterrupt(true)
+	defer catchInterrupt(false)
+	r := contextKey(group)
+	if r == 3 { // Ctrl-C
+		r = 27
+	}
+	recording = append(recording, r)
+	return r
+}
 
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
+func getTasks(filter string) ([]task, error) {
+	args, window, err := filterArgs(filter)
 	if err != nil {
 		return nil, err
 	}
+	// filterArgs has checked the window parses.
+	within, _ := parseWindow(window)
 
-	var tasks []task
-	err = json.Unmarshal(out.Bytes(), &tasks)
-	final := tasks[:0]
-	now := time.Now().UTC()
+	tasks, ok := db.match(args)
+	if !ok {
+		// Drop what's out of the window while decoding, so a broad filter
+		// doesn't hold every task it matched in memory.
+		err := runTaskStream(func(r io.Reader) error {
+			var err error
+			tasks, err = decodeTasks(r, windowMatch(window, within))
+			return err
+		}, append(args, "export")...)
+		if err != nil {
+			return nil, err
+		}
+		remember(tasks)
+	}
+	final := inWindow(tasks, window, within)
+	sortTasks(final)
+	return final, nil
+}
 
+// inWindow picks the tasks completed within the window, or the pending ones
+// without a window. Deleted tasks are left out.
+func inWindow(tasks []task, window string, within time.Duration) []task {
+	var final []task
+	keep := windowMatch(window, within)
 	for _, t := range tasks {
+		if t.badDates() {
+			lg.Errorf("Task %v has a missing or malformed entry %q or end %q",
+				t.Uuid, t.Created, t.Completed)
+		}
+		if keep(t) {
+			final = append(final, t)
+		}
+	}
+	return final
+}
+
+// windowMatch returns whether a task is in the window, as for inWindow.
+func windowMatch(window string, within time.Duration) func(task) bool {
+	now := time.Now().UTC()
+	return func(t task) bool {
 		if t.Status == "deleted" {
-			continue
+			return false
 		}
-		var end time.Time
-		if len(t.Completed) > 0 {
-			end, err = time.Parse(stamp, t.Completed)
-			if err != nil {
-				return tasks, err
-			}
+		// Tasks with a malformed end are treated as pending, rather than
+		// being lost.
+		end, _ := t.ended()
+		if len(window) > 0 {
+			return !end.IsZero() && (within == 0 || now.Sub(end) < within)
 		}
+		return end.IsZero()
+	}
+}
 
-		if completed > 0 {
-			if now.Sub(end) < time.Duration(completed)*7*24*time.Hour {
-				final = append(final, t)
+// wrapText wraps s into lines of at most width runes, indenting all but the
+// first by indent spaces. Newlines in s are kept.
+func wrapText(s string, width, indent int) string {
+	width -= indent
+	if width < 20 {
+		width = 20
+	}
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		var line []rune
+		for _, word := range strings.Fields(para) {
+			w := []rune(word)
+			if len(line) > 0 && len(line)+1+len(w) > width {
+				lines = append(lines, string(line))
+				line = line[:0:0]
 			}
-		} else {
-			if end.IsZero() {
-				final = append(final, t)
+			if len(line) > 0 {
+				line = append(line, ' ')
 			}
+			line = append(line, w...)
 		}
+		lines = append(lines, string(line))
 	}
-	sort.Sort(ByDefined(final))
-	return final, err
+	return strings.Join(lines, "\n"+strings.Repeat(" ", indent))
 }
 
-func singleCharMode() {
-	// disable input buffering
-	exec.Command("stty", "-F", "/dev/tty", "cbreak", "min", "1").Run()
-	// do not display entered characters on the screen
-	exec.Command("stty", "-F", "/dev/tty", "-echo").Run()
-}
-
-func lineInputMode() {
-	exec.Command("stty", "-F", "/dev/tty", "cooked").Run()
-	exec.Command("stty", "-F", "/dev/tty", "echo").Run()
-}
+// pageSize is how many tasks the list shows at a time.
+const pageSize = 30
 
-func showAndReviewTasks(orig []task) {
+// showAndReviewTasks lists the tasks, to review from. It returns true if it
+// left because the tasks changed elsewhere, while watching for that.
+func showAndReviewTasks(orig []task) bool {
 	fmt.Println()
 	var tasks []task
+	var page int
 
 	for _, tk := range orig {
 		if !showAll && tk.isReviewed() {
@@ -317,6 +604,7 @@ func showAndReviewTasks(orig []task) {
 	}
 
 SHOW:
+	printBanner("tasks")
 	switch sortBy {
 	case URGENCY:
 		fmt.Println("> Sorted by Urgency.")
@@ -324,114 +612,336 @@ SHOW:
 		fmt.Println("> Sorted by Color.")
 	case DATE:
 		fmt.Println("> Sorted by Date.")
+	case RANDOM:
+		fmt.Println("> Shuffled.")
 	}
 	fmt.Println()
 
-	for i, tk := range tasks {
-		if i >= 30 {
-			break
+	pages := (len(tasks) + pageSize - 1) / pageSize
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	var group string
+	now := time.Now()
+	start := now
+	// Only the page shown is rendered, however long the list.
+	from := page * pageSize
+	to := from + pageSize
+	if to > len(tasks) {
+		to = len(tasks)
+	}
+	for i := from; i < to; i++ {
+		tk := tasks[i]
+		if sortBy == DATE {
+			if g := completedGroup(tk, now); len(g) > 0 && g != group {
+				group = g
+				boldBlue.Printf("\n%s\n", g)
+			}
 		}
 		printSummary(tk, i, len(tasks))
 	}
 
-	fmt.Printf("\nFound %d tasks.\n", len(tasks))
+	if pages > 1 {
+		fmt.Printf("\nFound %d tasks. Page %d of %d.\n", len(tasks), page+1, pages)
+	} else {
+		fmt.Printf("\nFound %d tasks.\n", len(tasks))
+	}
 	short.Print("tasks", true)
-	b := make([]byte, 1)
-	os.Stdin.Read(b)
-	if b[0] == 10 { // Enter
-		return
+	timed("render list", start)
+	b, ok := watchKey("tasks")
+	if !ok {
+		// Changed elsewhere.
+		return true
+	}
+	if b == 10 { // Enter
+		return false
 	}
 
-	var i int
-	ins, _ := short.MapsTo(rune(b[0]), "tasks")
+	ins, _ := short.MapsTo(b, "tasks")
+	if len(ins) == 0 && b >= '0' && b <= '9' {
+		if i := quickOpen(b, len(tasks)); i != -1 {
+			reviewLoop(tasks, i)
+		}
+		return false
+	}
 	switch ins {
 	case "goto":
-		i = getJump()
-		if i == -1 {
-			break
+		if i := getJump(tasks); i != -1 {
+			reviewLoop(tasks, i)
 		}
-		fallthrough
 	case "review":
-		for i < len(tasks) {
-			if i < 0 || i >= len(tasks) {
-				break
+		reviewLoop(tasks, resumePosition(cur.Filter, tasks))
+	case "jump to active":
+		if act, ok := currentActivity(); ok && len(act.uuid) > 0 {
+			for i, tk := range tasks {
+				if tk.Uuid == act.uuid {
+					reviewLoop(tasks, i)
+					return false
+				}
 			}
-			tk := tasks[i]
-			move := printInfo(tk, i, len(tasks))
-			tasks[i] = getTask(tk.Uuid) // refresh.
-			i += move
+			reviewLoop([]task{cachedTask(act.uuid)}, 0)
 		}
+	case "stop active":
+		stopActivity()
+		clear()
+		goto SHOW
 	case "toggle show all":
 		showAll = !showAll
+	case "next page":
+		page++
+		clear()
+		goto SHOW
+	case "previous page":
+		page--
+		clear()
+		goto SHOW
 	case "fix":
-		for i := 0; i < len(tasks); i++ {
-			tk := &tasks[i]
-			if len(tk.colorTag()) == 0 {
-				fmt.Printf("Fixing task: %v\n", tk.Description)
-				tk.Tags = append(tk.Tags, "green")
-				tk.doImport()
-			}
-		}
+		triage(tasks)
+		clear()
+		goto SHOW
+	case "bulk by project", "bulk by user", "bulk by tag":
+		bulkAction(ins, tasks)
 		clear()
 		goto SHOW
 	case "sort by urgency":
 		sortBy = URGENCY
-		sort.Sort(ByDefined(tasks))
+		sortTasks(tasks)
 		clear()
 		goto SHOW
 	case "sort by date":
 		sortBy = DATE
-		sort.Sort(ByDefined(tasks))
+		sortTasks(tasks)
 		clear()
 		goto SHOW
 	case "sort by color":
 		sortBy = COLOR
-		sort.Sort(ByDefined(tasks))
+		sortTasks(tasks)
+		clear()
+		goto SHOW
+	case "shuffle":
+		sortBy = RANDOM
+		sortTasks(tasks)
 		clear()
 		goto SHOW
 	}
+	return false
 }
 
-func getJump() int {
-	lineInputMode()
-	defer singleCharMode()
+// bookmarks flags tasks to come back to later in the session, by uuid.
+var bookmarks = make(map[string]bool)
 
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("Jump to: ")
-	jump, err := reader.ReadString('\n')
-	if err != nil {
-		log.Fatal(err)
+// quitReview is the move which leaves the review loop.
+const quitReview = 1 << 30
+
+// notice is shown once, at the top of the next task view.
+var notice string
+
+// pace tracks how long tasks take to review in this session.
+var pace struct {
+	spent  time.Duration
+	viewed int
+}
+
+func printProgress(tasks []task) {
+	var done int
+	for _, tk := range tasks {
+		if tk.isReviewed() {
+			done++
+		}
 	}
-	j, err := strconv.Atoi(jump[:len(jump)-1])
-	if err != nil {
-		return -1
+	fmt.Printf("Reviewed %d of %d (%d%%)", done, len(tasks), 100*done/len(tasks))
+	if pace.viewed > 0 {
+		left := pace.spent / time.Duration(pace.viewed) * time.Duration(len(tasks)-done)
+		fmt.Printf(", ~%d min remaining at current pace", int(left.Minutes()+0.5))
 	}
-	return j
+	fmt.Println(".")
 }
 
-func clear() {
-	cmd := exec.Command("clear")
-	cmd.Stdout = os.Stdout
-	cmd.Run()
-	fmt.Println()
+// maxBreadcrumbs is how many recently viewed tasks are remembered.
+const maxBreadcrumbs = 20
+
+// breadcrumbs are the uuids of the recently viewed tasks, most recent last.
+// They survive resorting and refiltering.
+var breadcrumbs []string
+
+func pushBreadcrumb(uuid string) {
+	if n := len(breadcrumbs); n > 0 && breadcrumbs[n-1] == uuid {
+		return
+	}
+	breadcrumbs = append(breadcrumbs, uuid)
+	if len(breadcrumbs) > maxBreadcrumbs {
+		breadcrumbs = breadcrumbs[1:]
+	}
 }
 
-func searchTerms() string {
+// popBreadcrumb goes back to the task viewed before the current one. It
+// returns how far that task is in the list, or if it's no longer in the
+// list, shows it on its own and stays put.
+func popBreadcrumb(tasks []task, idx int) int {
+	if len(breadcrumbs) < 2 {
+		return 0
+	}
+	breadcrumbs = breadcrumbs[:len(breadcrumbs)-1]
+	uuid := breadcrumbs[len(breadcrumbs)-1]
+	for j, tk := range tasks {
+		if tk.Uuid == uuid {
+			return j - idx
+		}
+	}
+	reviewLoop([]task{cachedTask(uuid)}, 0)
+	pushBreadcrumb(tasks[idx].Uuid)
+	return 0
+}
+
+// nextMatching returns how far the next task after idx which matches is,
+// wrapping around to the start of the list. It returns 0 if none do.
+func nextMatching(tasks []task, idx int, match func(tk task) bool) int {
+	for j := 1; j < len(tasks); j++ {
+		k := (idx + j) % len(tasks)
+		if match(tasks[k]) {
+			return k - idx
+		}
+	}
+	return 0
+}
+
+// reviewLoop shows the tasks one by one, starting at index i, until
+// stepping off either end of the list.
+func reviewLoop(tasks []task, i int) {
+	if len(tasks) == 0 {
+		return
+	}
+	defer func() {
+		cur.Uuid = ""
+		saveSession()
+	}()
+	for i < len(tasks) {
+		if i < 0 || i >= len(tasks) {
+			break
+		}
+		tasks[i] = tasks[i].full()
+		tk := tasks[i]
+		cur.Uuid = tk.Uuid
+		saveSession()
+		pushBreadcrumb(tk.Uuid)
+		start := time.Now()
+		move := printInfo(tasks, i)
+		pace.spent += time.Since(start)
+		pace.viewed++
+		if i+move >= len(tasks) && !*wrap {
+			// Reached the end, so there's nothing left to continue from.
+			savePosition(cur.Filter, "")
+		}
+		tasks[i] = cachedTask(tk.Uuid) // refresh.
+		if move == quitReview {
+			savePosition(cur.Filter, tk.Uuid)
+			return
+		}
+		i += move
+		if *wrap && (i < 0 || i >= len(tasks)) {
+			if i < 0 {
+				i, notice = len(tasks)-1, "Wrapped around to the end of the list."
+			} else {
+				i, notice = 0, "Wrapped around to the start of the list."
+			}
+		}
+	}
+}
+
+// digitWait is how long quickOpen waits for the next digit of an index.
+const digitWait = 700 * time.Millisecond
+
+// quickOpen reads the index of a task in the list, as shown against it,
+// starting with the digit already pressed. It opens as soon as no further
+// digit could make a valid index, on Enter, or once no digit comes within
+// digitWait. It returns -1 if the index is out of the list, or on Esc.
+func quickOpen(first rune, total int) int {
+	idx := int(first - '0')
+	for {
+		fmt.Printf("\r\033[KOpen %d", idx)
+		if idx*10 >= total {
+			break
+		}
+		r, ok := readKeyTimeout(digitWait)
+		if !ok || r == 10 {
+			break
+		}
+		if r == 27 { // Esc
+			return -1
+		}
+		if r < '0' || r > '9' {
+			break
+		}
+		idx = idx*10 + int(r-'0')
+	}
 	fmt.Println()
-	lineInputMode()
-	defer singleCharMode()
+	if idx >= total {
+		return -1
+	}
+	return idx
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("Enter search terms: ")
-	desc, err := reader.ReadString('\n')
-	if err != nil {
-		log.Fatal(err)
+// getJump asks for the task to jump to, by its index in the list, its
+// taskwarrior ID as #id, a fragment of its UUID, or a part of its
+// description. It returns -1 if nothing matches.
+func getJump(tasks []task) int {
+	jump := readLine("Jump to (index, #id, uuid or description): ")
+	if j, err := strconv.Atoi(jump); err == nil && j >= 0 && j < len(tasks) {
+		return j
 	}
-	return strings.Trim(desc, " \n")
+	if len(jump) == 0 {
+		return -1
+	}
+	if strings.HasPrefix(jump, "#") {
+		id, err := strconv.Atoi(jump[1:])
+		if err != nil {
+			return -1
+		}
+		for i, tk := range tasks {
+			if tk.Id == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// An 8 hex digit fragment can match anywhere in the UUID, while
+	// anything else has to be a prefix of it.
+	frag := strings.ToLower(jump)
+	anywhere := uuidExp.MatchString(frag)
+	if anywhere {
+		frag = uuidExp.FindString(frag)
+	}
+	for i, tk := range tasks {
+		if strings.HasPrefix(tk.Uuid, frag) || (anywhere && strings.Contains(tk.Uuid, frag)) {
+			return i
+		}
+	}
+	for i, tk := range tasks {
+		if strings.Contains(strings.ToLower(tk.Description), strings.ToLower(jump)) {
+			return i
+		}
+	}
+	return -1
+}
+
+func clear() {
+	// Move home and clear the screen, as clear(1) does.
+	fmt.Print("\033[H\033[2J")
+	fmt.Println()
+}
+
+// readLine shows the prompt and reads back a line of input.
+func readLine(prompt string) string {
+	return strings.Trim(editLine(prompt), " ")
 }
 
 func runShell(filter string) string {
 	clear()
+	checkPomodoro()
 	short.Print("help", true)
 	fmt.Println()
 	color.New(color.BgBlue, color.FgWhite).Printf("task %s>", filter)
@@ -439,12 +949,20 @@ func runShell(filter string) string {
 	r := make([]byte, 1)
 	os.Stdin.Read(r)
 	if r[0] == 10 { // Enter
-		if len(filter) > 0 {
+		for len(filter) > 0 {
 			uuids, err := getTasks(filter)
 			if err != nil {
-				log.Fatal(err)
+				lg.Fatalf("While getting tasks for filter %q: %v", filter, err)
+			}
+			printTrend(filter, uuids)
+			if !showAndReviewTasks(uuids) {
+				break
+			}
+			// The tasks changed elsewhere, so list them afresh.
+			if err := db.load(); err != nil {
+				lg.Fatalf("%v", err)
 			}
-			showAndReviewTasks(uuids)
+			clear()
 		}
 		return filter
 	}
@@ -456,10 +974,24 @@ func runShell(filter string) string {
 	case "clear":
 		return ""
 	case "completed":
-		return filter + " _end"
+		return askWindow(filter)
+	case "mark old reviewed":
+		reviewOldCompleted(filter)
 	case "search":
-		terms := searchTerms()
-		return filter + " " + terms
+		f := addTerms(filter, liveSearch(filter))
+		if _, _, err := filterArgs(f); err != nil {
+			boldRed.Printf("%v. Press any key to continue.\n", err)
+			readKey()
+			return filter
+		}
+		return f
+	case "review by assignee":
+		ch := showAndGetResponse("Assignee (Enter for all)", "user")
+		if ch == 10 {
+			reviewByAssignee(filter, "")
+		} else if a, ok := short.MapsTo(ch, "user"); ok {
+			reviewByAssignee(filter, "@"+a)
+		}
 
 	case "assigned":
 		ch := showAndGetResponse("Assign To", "user")
@@ -476,6 +1008,48 @@ func runShell(filter string) string {
 		if a, ok := short.MapsTo(ch, "tag"); ok {
 			return filter + " +" + a
 		}
+	case "dashboard":
+		showDashboard(filter)
+	case "report":
+		showReport(filter)
+	case "retro":
+		showRetro(filter)
+	case "time today":
+		showDayTime()
+	case "archive":
+		archiveTasks(filter, false)
+	case "keys":
+		rebindKeys()
+	case "refresh":
+		if err := db.load(); err != nil {
+			lg.Fatalf("%v", err)
+		}
+	case "changelog":
+		showChangelog(filter)
+	case "load filter":
+		path := readLine("Filter file: ")
+		if len(path) == 0 {
+			return filter
+		}
+		f, err := readFilterFile(path)
+		if err != nil {
+			boldRed.Printf("%v. Press any key to continue.\n", err)
+			readKey()
+			return filter
+		}
+		return f
+	case "legend":
+		showLegend()
+	case "color":
+		ch := showAndGetResponse("Color", "color")
+		if a, ok := short.MapsTo(ch, "color"); ok {
+			return filter + " +" + a
+		}
+	case "disputes":
+		ch := showAndGetResponse("Dispute State", "dispute")
+		if a, ok := short.MapsTo(ch, "dispute"); ok {
+			return filter + " +" + a
+		}
 	case "new":
 		args := strings.Split(filter, " ")
 		var project, user string
@@ -504,7 +1078,7 @@ func runShell(filter string) string {
 			}
 		}
 
-		tags := []string{user, "green"}
+		tags := []string{user, cfg.FixState}
 		t := task{
 			Project: project,
 			Status:  "pending",
@@ -522,7 +1096,7 @@ func runShell(filter string) string {
 func generateMappings() {
 	tasks, err := getTasks("")
 	if err != nil {
-		log.Fatal(err)
+		lg.Fatalf("While getting all tasks: %v", err)
 	}
 	for _, task := range tasks {
 		if len(task.Completed) > 0 || task.Status == "deleted" {
@@ -538,25 +1112,61 @@ func generateMappings() {
 				short.AutoAssign(t, "tag")
 			} else if t[0] == '@' {
 				short.AutoAssign(t[1:], "user")
+				knownUsers[t[1:]] = true
 			}
 		} // end tags
 	}
 
-	short.BestEffortAssign('r', "red", "color")
-	short.BestEffortAssign('b', "blue", "color")
-	short.BestEffortAssign('g', "green", "color")
+	for _, s := range cfg.States {
+		if len(s.Key) > 0 {
+			short.BestEffortAssign(rune(s.Key[0]), s.Name, "color")
+		} else {
+			short.AutoAssign(s.Name, "color")
+		}
+	}
 
 	short.BestEffortAssign('q', "quit", "help")
 	short.BestEffortAssign('c', "clear", "help")
 	short.BestEffortAssign('d', "completed", "help")
+	short.BestEffortAssign('m', "mark old reviewed", "help")
 	short.BestEffortAssign('a', "assigned", "help")
 	short.BestEffortAssign('p', "project", "help")
 	short.BestEffortAssign('n', "new", "help")
 	short.BestEffortAssign('t', "tag", "help")
+	short.BestEffortAssign('o', "color", "help")
 	short.BestEffortAssign('s', "search", "help")
+	short.BestEffortAssign('u', "review by assignee", "help")
+	short.BestEffortAssign('i', "disputes", "help")
+	short.BestEffortAssign('b', "dashboard", "help")
+	short.BestEffortAssign('r', "report", "help")
+	short.BestEffortAssign('l', "retro", "help")
+	short.BestEffortAssign('h', "time today", "help")
+	short.BestEffortAssign('v', "archive", "help")
+	short.BestEffortAssign('g', "changelog", "help")
+	short.BestEffortAssign('f', "refresh", "help")
+	short.BestEffortAssign('k', "keys", "help")
+	short.BestEffortAssign('F', "load filter", "help")
+	short.BestEffortAssign('?', "legend", "help")
 
 	short.BestEffortAssign('e', "description", "task")
 	short.BestEffortAssign('a', "assigned", "task")
+	short.BestEffortAssign('g', "delegate", "task")
+	short.BestEffortAssign('o', "pomodoro", "task")
+	short.BestEffortAssign('s', "start", "task")
+	short.BestEffortAssign('S', "stop", "task")
+	short.BestEffortAssign('C', "client", "task")
+	short.BestEffortAssign('$', "billable", "task")
+	short.BestEffortAssign('j', "jump to active", "task")
+	short.BestEffortAssign('X', "stop active", "task")
+	short.BestEffortAssign('f', "bookmark", "task")
+	short.BestEffortAssign('n', "next bookmark", "task")
+	short.BestEffortAssign('u', "next unreviewed", "task")
+	short.BestEffortAssign('h', "breadcrumb back", "task")
+	short.BestEffortAssign('B', "blocker", "task")
+	short.BestEffortAssign('.', "repeat", "task")
+	short.BestEffortAssign('D', "next disputed", "task")
+	short.BestEffortAssign('+', "add pomodoro", "task")
+	short.BestEffortAssign('l', "retro note", "task")
 	short.BestEffortAssign('p', "project", "task")
 	short.BestEffortAssign('c', "color", "task")
 	short.BestEffortAssign('t', "tags", "task")
@@ -565,7 +1175,16 @@ func generateMappings() {
 	short.BestEffortAssign('q', "quit", "task")
 	short.BestEffortAssign('x', "delete", "task")
 	short.BestEffortAssign('d', "done", "task")
+	short.BestEffortAssign('O', "reopen", "task")
 	short.BestEffortAssign('i', "disputed", "task")
+	short.BestEffortAssign('m', "reply to dispute", "task")
+	short.BestEffortAssign('k', "acknowledge dispute", "task")
+	short.BestEffortAssign('v', "resolve dispute", "task")
+	assignMacros()
+
+	short.BestEffortAssign('d', kDisputed, "dispute")
+	short.BestEffortAssign('a', kAcknowledged, "dispute")
+	short.BestEffortAssign('r', kResolved, "dispute")
 
 	short.BestEffortAssign('f', "fix", "tasks")
 	short.BestEffortAssign('a', "toggle show all", "tasks")
@@ -573,23 +1192,60 @@ func generateMappings() {
 	short.BestEffortAssign('u', "sort by urgency", "tasks")
 	short.BestEffortAssign('d', "sort by date", "tasks")
 	short.BestEffortAssign('c', "sort by color", "tasks")
+	short.BestEffortAssign('s', "shuffle", "tasks")
+	short.BestEffortAssign('n', "next page", "tasks")
+	short.BestEffortAssign('p', "previous page", "tasks")
 	short.BestEffortAssign('g', "goto", "tasks")
+	short.BestEffortAssign('P', "bulk by project", "tasks")
+	short.BestEffortAssign('U', "bulk by user", "tasks")
+	short.BestEffortAssign('T', "bulk by tag", "tasks")
+	short.BestEffortAssign('j', "jump to active", "tasks")
+	short.BestEffortAssign('S', "stop active", "tasks")
+
+	short.BestEffortAssign('r', "reviewed", "bulk")
+	short.BestEffortAssign('d', "done", "bulk")
+	short.BestEffortAssign('c', "color", "bulk")
+	short.BestEffortAssign('a', "assign", "bulk")
 }
 
 func main() {
+	flag.Usage = usage
 	flag.Parse()
-	short = keys.ParseConfig(*config)
-	generateMappings()
+	if *setup {
+		printSetup()
+		return
+	}
+	os.Exit(run())
+}
 
-	fmt.Println("Taskreview version 0.1")
-	filter := *cmdfilter
-	singleCharMode()
-	for {
-		filter = runShell(filter)
-		if filter == "-1" {
-			break
+// run runs the command picked on the commandline, and returns the exit code.
+func run() int {
+	initLogger()
+	defer lg.Close()
+
+	// Without a command, review, as before there were commands.
+	name, args := "review", flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := findCommand(name)
+	if !ok {
+		lg.Fatalf("Unknown command %q. Run with -h for the commands.", name)
+	}
+	if !lockFree[name] {
+		if err := acquireLock(); err != nil {
+			lg.Fatalf("Unable to start: %v", err)
 		}
-		filter = strings.Trim(filter, " \n")
+		defer releaseLock()
+	}
+	cmd.flags.Parse(args)
+	switch err := cmd.run(); {
+	case err == errUnreviewed:
+		return exitUnreviewed
+	case err != nil:
+		lg.Errorf("%s failed: %v", name, err)
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", name, err)
+		return exitError
 	}
-	short.Persist(*config)
+	return exitClear
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// helpKey shows the shortcuts of the current screen or prompt, wherever it
+// isn't mapped to something else.
+const helpKey = '?'
+
+// contextKey reads a key for the group, showing its shortcuts over the
+// screen for as long as helpKey is pressed.
+func contextKey(group string) rune {
+	for {
+		r := readKey()
+		if !isHelpKey(r, group) {
+			return r
+		}
+		showOverlay(group)
+	}
+}
+
+// isHelpKey returns whether r is helpKey, and not mapped in the group.
+func isHelpKey(r rune, group string) bool {
+	if r != helpKey || len(group) == 0 {
+		return false
+	}
+	_, ok := short.MapsTo(r, group)
+	return !ok
+}
+
+// showOverlay lists the shortcuts of the group on the alternate screen, so
+// that going back leaves the screen underneath as it was.
+func showOverlay(group string) {
+	label := group
+	for _, g := range keyGroups {
+		if g.group == group {
+			label = g.label
+		}
+	}
+	fmt.Print("\033[?1049h\033[H\033[2J")
+	defer fmt.Print("\033[?1049l")
+
+	boldBlue.Printf("%s shortcuts\n\n", label)
+	for _, b := range short.table(group) {
+		color.New(color.BgWhite, color.FgBlack).Printf(" %c ", b.key)
+		fmt.Printf(" %s\n", b.name)
+	}
+	if leaderGroups[group] {
+		color.New(color.BgWhite, color.FgBlack).Printf(" %c ", leaderKey)
+		fmt.Println(" pick by name")
+	}
+	fmt.Println("\nPress any key to go back.")
+	readKey()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+var (
+	pomodoroLength = flag.Duration("pomodoro", 25*time.Minute,
+		"Length of a pomodoro started from the task view.")
+	notify = flag.Bool("notify", false,
+		"Fire a desktop notification via -notifier when a pomodoro completes.")
+	notifier = flag.String("notifier", "notify-send",
+		"Command to send notifications with. It's given a title and a message.")
+)
+
+// pomodoro is the timer running for a task. There's at most one at a time.
+type pomodoro struct {
+	sync.Mutex
+	uuid  string
+	desc  string
+	start time.Time
+	stop  chan struct{}
+}
+
+var pomo pomodoro
+
+// startPomodoro starts a pomodoro for the task, replacing any running one.
+// The countdown is shown in the terminal title.
+func (t task) startPomodoro() int {
+	pomo.Lock()
+	defer pomo.Unlock()
+	if pomo.stop != nil {
+		close(pomo.stop)
+	}
+	pomo.uuid, pomo.desc = t.Uuid, t.Description
+	pomo.start = time.Now().UTC()
+	pomo.stop = make(chan struct{})
+	lg.Infof("Started pomodoro for task %v", t.Uuid)
+	go tickPomodoro(pomo.desc, pomo.start.Add(*pomodoroLength), pomo.stop)
+	return 0
+}
+
+func tickPomodoro(desc string, end time.Time, stop chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		left := end.Sub(time.Now())
+		if left <= 0 {
+			fmt.Fprintf(os.Stdout, "\033]0;Pomodoro done: %s\007", desc)
+			if *notify {
+				sendNotification("Pomodoro done", desc)
+			}
+			return
+		}
+		fmt.Fprintf(os.Stdout, "\033]0;%s left: %s\007", fmtCountdown(left), desc)
+	}
+}
+
+// sendNotification runs the notifier, logging if it fails.
+func sendNotification(title, msg string) error {
+	_, err := runCmd(exec.Command(*notifier, title, msg))
+	if err != nil {
+		lg.Errorf("While sending notification %q: %v", title, err)
+	}
+	return err
+}
+
+func (t task) addPomodoro() int {
+	t.Pomodoros++
+	t.doImport()
+	return 0
+}
+
+func fmtCountdown(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// checkPomodoro annotates the task once its pomodoro has completed, and
+// prints the countdown of a running one. It's run on every screen refresh,
+// so that all task writes stay on the main goroutine.
+func checkPomodoro() {
+	pomo.Lock()
+	if len(pomo.uuid) == 0 {
+		pomo.Unlock()
+		return
+	}
+	end := pomo.start.Add(*pomodoroLength)
+	left := end.Sub(time.Now().UTC())
+	if left > 0 {
+		stateColor(cfg.States[0].Name).Printf(" Pomodoro %s left: %s ", fmtCountdown(left), pomo.desc)
+		fmt.Println()
+		pomo.Unlock()
+		return
+	}
+	uuid := pomo.uuid
+	pomo.uuid, pomo.stop = "", nil
+	pomo.Unlock()
+
+	tk := getTask(uuid)
+	tk.Pomodoros++
+	tk.Annotations = append(tk.Annotations, annotation{
+		Entry:       end.Format(stamp),
+		Description: fmt.Sprintf("pomodoro completed (%v)", *pomodoroLength),
+	})
+	tk.doImport()
+	boldGreen.Printf("Pomodoro completed for: %s\n", tk.Description)
+}
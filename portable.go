@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// portable bundles the shortcuts and settings into one file, to carry the
+// same setup over to another machine.
+type portable struct {
+	// Keys is the keys file, as is.
+	Keys     string          `json:"keys,omitempty"`
+	Bindings json.RawMessage `json:"bindings,omitempty"`
+	LastKeys json.RawMessage `json:"last_keys,omitempty"`
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+// bundled is a file in the bundle, and where it goes.
+type bundled struct {
+	path string
+	raw  *json.RawMessage
+}
+
+func (p *portable) files() []bundled {
+	return []bundled{
+		{*bindingsPath, &p.Bindings},
+		{*lastKeysPath, &p.LastKeys},
+		{*settingsPath, &p.Settings},
+	}
+}
+
+// exportConfig writes the keys file, rebound shortcuts and settings to path.
+func exportConfig(path string) error {
+	if len(path) == 0 {
+		return errors.New("usage: taskreview export-config <file>")
+	}
+	var p portable
+	if data, err := ioutil.ReadFile(*config); err == nil {
+		p.Keys = string(data)
+	}
+	for _, f := range p.files() {
+		if data, err := ioutil.ReadFile(f.path); err == nil {
+			*f.raw = data
+		}
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "while marshalling config")
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return errors.Wrapf(err, "while writing %q", path)
+	}
+	fmt.Printf("Exported shortcuts and settings to %q.\n", path)
+	return nil
+}
+
+// importConfig puts the files bundled in path in place, once confirmed. The
+// files it replaces are kept alongside, with a .bak suffix.
+func importConfig(path string) error {
+	if len(path) == 0 {
+		return errors.New("usage: taskreview import-config <file>")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "while reading %q", path)
+	}
+	var p portable
+	if err := json.Unmarshal(data, &p); err != nil {
+		return errors.Wrapf(err, "while parsing %q", path)
+	}
+	writes := map[string][]byte{}
+	if len(p.Keys) > 0 {
+		writes[*config] = []byte(p.Keys)
+	}
+	for _, f := range p.files() {
+		if len(*f.raw) > 0 {
+			writes[f.path] = *f.raw
+		}
+	}
+	if len(writes) == 0 {
+		return errors.Errorf("nothing to import in %q", path)
+	}
+	fmt.Println("This replaces:")
+	for dst := range writes {
+		fmt.Printf("  %s\n", dst)
+	}
+	fmt.Printf("Continue? [y/N] ")
+	var answer string
+	fmt.Scanln(&answer)
+	if answer != "y" && answer != "Y" {
+		return nil
+	}
+	for dst, data := range writes {
+		if _, err := os.Stat(dst); err == nil {
+			if err := os.Rename(dst, dst+".bak"); err != nil {
+				return errors.Wrapf(err, "while backing up %q", dst)
+			}
+		}
+		if err := ioutil.WriteFile(dst, data, 0644); err != nil {
+			return errors.Wrapf(err, "while writing %q", dst)
+		}
+	}
+	fmt.Printf("Imported shortcuts and settings from %q.\n", path)
+	return nil
+}
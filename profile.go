@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var profile = flag.Bool("profile", false,
+	"Time every task invocation, JSON decode and render, and print a breakdown at exit.")
+
+type timing struct {
+	count      int
+	total, max time.Duration
+}
+
+// timings are keyed by what was timed, as in "task export" or "render list".
+// Tasks run from goroutines too, like the pomodoro's notification.
+var timings = struct {
+	sync.Mutex
+	m map[string]*timing
+}{m: make(map[string]*timing)}
+
+// timed records how long the named step took since start. Use it as:
+//
+//	defer timed("decode", time.Now())
+func timed(name string, start time.Time) {
+	if !*profile {
+		return
+	}
+	d := time.Since(start)
+	timings.Lock()
+	defer timings.Unlock()
+	t, ok := timings.m[name]
+	if !ok {
+		t = new(timing)
+		timings.m[name] = t
+	}
+	t.count++
+	t.total += d
+	if d > t.max {
+		t.max = d
+	}
+}
+
+// printProfile prints the timings, slowest in total first.
+func printProfile() {
+	if !*profile {
+		return
+	}
+	timings.Lock()
+	defer timings.Unlock()
+	if len(timings.m) == 0 {
+		return
+	}
+	names := make([]string, 0, len(timings.m))
+	for n := range timings.m {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return timings.m[names[i]].total > timings.m[names[j]].total
+	})
+	boldBlue.Printf("\n%-24s %8s %12s %12s %12s\n", "Step", "Count", "Total", "Mean", "Max")
+	for _, n := range names {
+		t := timings.m[n]
+		fmt.Printf("%-24s %8d %12v %12v %12v\n", n, t.count, t.total.Round(time.Microsecond),
+			(t.total / time.Duration(t.count)).Round(time.Microsecond), t.max.Round(time.Microsecond))
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	remindFlags = commandFlags("remind", filterArg)
+	remindSend  = remindFlags.Bool("send", false,
+		"Send the reminder through -notifier, instead of printing it.")
+)
+
+// runRemind reminds about the pending tasks whose review has lapsed, if any.
+// It's quiet otherwise, so that cron only mails when there's a review to do.
+func runRemind() error {
+	loadSettings()
+	filter := commandFilter(remindFlags)
+	tasks, err := getTasks(filter)
+	if err != nil {
+		return errors.Wrapf(err, "while getting tasks for filter %q", filter)
+	}
+	var lapsed, never int
+	for _, tk := range tasks {
+		if tk.isReviewed() {
+			continue
+		}
+		if _, ok := tk.reviewedAt(*reviewTag); ok {
+			lapsed++
+		} else {
+			never++
+		}
+	}
+	if lapsed+never == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("%d tasks need a review: %d lapsed since the last one, %d never reviewed.",
+		lapsed+never, lapsed, never)
+	if *remindSend {
+		if err := sendNotification("Time to review", msg); err != nil {
+			return err
+		}
+		return errUnreviewed
+	}
+	fmt.Println(msg)
+	return errUnreviewed
+}
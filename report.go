@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+type tally struct {
+	tasks     int
+	completed int
+	pomodoros int
+}
+
+func printTallies(header string, tallies map[string]*tally) {
+	keys := make([]string, 0, len(tallies))
+	for k := range tallies {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	boldBlue.Printf("%-20s %8s %10s %10s\n", header, "Tasks", "Completed", "Pomodoros")
+	var total tally
+	for _, k := range keys {
+		t := tallies[k]
+		fmt.Printf("%-20s %8d %10d %10d\n", k, t.tasks, t.completed, t.pomodoros)
+		total.tasks += t.tasks
+		total.completed += t.completed
+		total.pomodoros += t.pomodoros
+	}
+	fmt.Printf("%-20s %8d %10d %10d\n\n", "Total", total.tasks, total.completed,
+		total.pomodoros)
+}
+
+// showReport shows the report for the filter, until a key is pressed.
+func showReport(filter string) {
+	clear()
+	if err := printReport(filter); err != nil {
+		lg.Fatalf("While getting tasks for filter %q: %v", filter, err)
+	}
+	fmt.Println("Press any key to go back.")
+	readKey()
+}
+
+// printReport prints the per-project, per-user and per-color breakdown of
+// the tasks matching the filter. With a completed window, that's who shipped
+// what over the window.
+func printReport(filter string) error {
+	tasks, err := getTasks(filter)
+	if err != nil {
+		return err
+	}
+	byProject := make(map[string]*tally)
+	byUser := make(map[string]*tally)
+	byColor := make(map[string]*tally)
+	add := func(m map[string]*tally, key string, tk task) {
+		if len(key) == 0 {
+			key = unassigned
+		}
+		t, ok := m[key]
+		if !ok {
+			t = new(tally)
+			m[key] = t
+		}
+		t.tasks++
+		if len(tk.Completed) > 0 {
+			t.completed++
+		}
+		t.pomodoros += tk.Pomodoros
+	}
+	for _, tk := range tasks {
+		add(byProject, tk.Project, tk)
+		add(byUser, tk.userTag(), tk)
+		add(byColor, tk.colorTag(), tk)
+	}
+
+	if _, window := splitWindow(filter); len(window) > 0 {
+		boldGreen.Printf("Completed in the last %s\n\n", window)
+	}
+	printTallies("Project", byProject)
+	printTallies("User", byUser)
+	printTallies("Color", byColor)
+	printAccuracy(tasks)
+	return nil
+}
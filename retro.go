@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// retroPrefix marks annotations which are notes for the retro.
+const retroPrefix = "retro "
+
+// addRetroNote records something learned from the task, for the retro.
+func (t task) addRetroNote() int {
+	fmt.Println()
+	text := readLine("Retro note: ")
+	if len(text) == 0 {
+		return 0
+	}
+	t.annotate(retroPrefix + "@" + reviewerName(*reviewTag) + ": " + text)
+	t.doImport()
+	return 0
+}
+
+// retroNotes returns the retro annotations of the task.
+func (tk task) retroNotes() []annotation {
+	var notes []annotation
+	for _, a := range tk.Annotations {
+		if strings.HasPrefix(a.Description, retroPrefix) {
+			notes = append(notes, a)
+		}
+	}
+	return notes
+}
+
+// showRetro collects the retro notes across the tasks matching the filter,
+// usually those completed in the window, into one document. It can be saved
+// to a file, to share.
+func showRetro(filter string) {
+	tasks, err := getTasks(filter)
+	if err != nil {
+		lg.Fatalf("While getting tasks for filter %q: %v", filter, err)
+	}
+	var doc bytes.Buffer
+	_, window := splitWindow(filter)
+	if len(window) > 0 {
+		fmt.Fprintf(&doc, "Retro notes, for tasks completed in the last %s\n", window)
+	} else {
+		fmt.Fprintf(&doc, "Retro notes\n")
+	}
+	fmt.Fprintf(&doc, "Filter: %s\n", filter)
+	var count int
+	for _, tk := range tasks {
+		if tk.noteCount() == 0 {
+			continue
+		}
+		notes := tk.full().retroNotes()
+		if len(notes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&doc, "\n%s", tk.Description)
+		if len(tk.Project) > 0 {
+			fmt.Fprintf(&doc, " [%s]", tk.Project)
+		}
+		if u := tk.userTag(); len(u) > 0 {
+			fmt.Fprintf(&doc, " %s", u)
+		}
+		fmt.Fprintln(&doc)
+		for _, a := range notes {
+			var when string
+			if ts, err := time.Parse(stamp, a.Entry); err == nil {
+				when = ts.Format(format) + "  "
+			}
+			fmt.Fprintf(&doc, "  - %s%s\n", when, strings.TrimPrefix(a.Description, retroPrefix))
+			count++
+		}
+	}
+	fmt.Fprintf(&doc, "\n%d notes.\n", count)
+
+	showDoc(doc.Bytes())
+}
+
+// showDoc prints a generated document, and offers to save it to a file.
+func showDoc(doc []byte) {
+	clear()
+	fmt.Print(string(doc))
+	fmt.Println()
+	path := readLine("Save to file (Enter to skip): ")
+	if len(path) == 0 {
+		return
+	}
+	if err := ioutil.WriteFile(path, doc, 0644); err != nil {
+		boldRed.Printf("Unable to save to %q: %v\n", path, err)
+	} else {
+		fmt.Printf("Saved to %q.\n", path)
+	}
+	fmt.Println("Press any key to go back.")
+	readKey()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// searchDebounce is how long typing has to pause, before the search runs.
+const searchDebounce = 150 * time.Millisecond
+
+// searchPreview is how many matching tasks are shown while typing.
+const searchPreview = 10
+
+type searchResult struct {
+	query   string
+	tasks   []task
+	matched bool
+}
+
+// liveSearch reads search terms a key at a time, showing the tasks they'd
+// match within the filter as they're typed. Matching runs over the store,
+// once typing pauses, and a run still going is abandoned on the next key.
+// The terms are edited with lineEditor. Enter returns them, and Esc returns
+// none.
+func liveSearch(filter string) string {
+	// Load the store up front, rather than from a search run.
+	if db.loaded.IsZero() {
+		if err := db.load(); err != nil {
+			lg.Fatalf("%v", err)
+		}
+	}
+	// Have reads return every tenth of a second, to notice pauses.
+	setRaw(0, 1)
+	defer singleCharMode()
+
+	e := newLineEditor("search")
+	var last searchResult
+	var cancel chan struct{}
+	results := make(chan searchResult, 1)
+	typed, dirty := time.Now(), true
+	render := func() {
+		clear()
+		color.New(color.BgBlue, color.FgWhite).Printf("task %s>", filter)
+		fmt.Print(" Search: ")
+		e.printCursor()
+		fmt.Print("\n\n")
+		if last.query != e.String() {
+			return
+		}
+		if !last.matched {
+			fmt.Println("(Filter needs taskwarrior. Press Enter to search.)")
+			return
+		}
+		for i, tk := range last.tasks {
+			if i >= searchPreview {
+				fmt.Printf("... and %d more.\n", len(last.tasks)-searchPreview)
+				break
+			}
+			printSummary(tk, i, len(last.tasks))
+		}
+		fmt.Printf("\nFound %d tasks.\n", len(last.tasks))
+	}
+	render()
+
+	b := make([]byte, 1)
+	more := func() (rune, bool) {
+		if n, _ := os.Stdin.Read(b); n == 0 {
+			return 0, false
+		}
+		return rune(b[0]), true
+	}
+	for {
+		if n, _ := os.Stdin.Read(b); n == 0 {
+			select {
+			case res := <-results:
+				last = res
+				render()
+			default:
+			}
+			if dirty && time.Since(typed) >= searchDebounce {
+				dirty = false
+				cancel = make(chan struct{})
+				go runSearch(filter, e.String(), cancel, results)
+			}
+			continue
+		}
+		before := e.String()
+		switch e.key(rune(b[0]), more) {
+		case lineEntered:
+			if cancel != nil {
+				close(cancel)
+			}
+			return e.String()
+		case lineCancelled:
+			if cancel != nil {
+				close(cancel)
+			}
+			return ""
+		}
+		if e.String() == before {
+			// Only the cursor moved.
+			render()
+			continue
+		}
+		if cancel != nil {
+			close(cancel)
+			cancel = nil
+		}
+		typed, dirty = time.Now(), true
+		render()
+	}
+}
+
+// runSearch matches the query within the filter over the store, and sends
+// the result unless cancelled first.
+func runSearch(filter, query string, cancel chan struct{}, results chan<- searchResult) {
+	res := searchResult{query: query}
+	args, window, err := filterArgs(addTerms(filter, query))
+	if err == nil {
+		within, _ := parseWindow(window)
+		var tasks []task
+		if tasks, res.matched = db.matchUntil(args, cancel); res.matched {
+			res.tasks = inWindow(tasks, window, within)
+			sortTasks(res.tasks)
+		}
+	}
+	select {
+	case <-cancel:
+	case results <- res:
+	}
+}
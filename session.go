@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+var (
+	sessionPath = flag.String("session", os.Getenv("HOME")+"/.taskreview.session",
+		"Path to persist the in-progress session to, so it can be resumed after a crash.")
+	positionsPath = flag.String("positions", os.Getenv("HOME")+"/.taskreview.positions",
+		"Path to remember, per filter, where a review was left off.")
+)
+
+// session is the state needed to pick up where a session left off. The
+// file only exists while a session is running, so finding one at startup
+// means the previous session ended abnormally.
+type session struct {
+	Filter  string `json:"filter"`
+	SortBy  int    `json:"sort_by"`
+	ShowAll bool   `json:"show_all"`
+	// Uuid is the task being reviewed, if any.
+	Uuid string `json:"uuid,omitempty"`
+}
+
+var cur session
+
+// saveSession persists the current session state. It's called whenever
+// that state changes.
+func saveSession() {
+	cur.SortBy, cur.ShowAll = sortBy, showAll
+	data, err := json.Marshal(cur)
+	if err != nil {
+		lg.Errorf("While marshalling session: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(*sessionPath, data, 0600); err != nil {
+		lg.Errorf("While saving session to %q: %v", *sessionPath, err)
+	}
+}
+
+// endSession marks the session as having ended cleanly.
+func endSession() {
+	if err := os.Remove(*sessionPath); err != nil && !os.IsNotExist(err) {
+		lg.Errorf("While removing session %q: %v", *sessionPath, err)
+	}
+}
+
+// loadSession returns the session left behind by a previous run, if any.
+func loadSession() (session, bool) {
+	var s session
+	data, err := ioutil.ReadFile(*sessionPath)
+	if err != nil {
+		return s, false
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		lg.Errorf("While parsing session %q: %v", *sessionPath, err)
+		return s, false
+	}
+	return s, true
+}
+
+// offerResume asks whether to resume a session which ended abnormally. If
+// so, it restores the filter and sort, and continues the review from the
+// task it was on. It returns the filter to continue with.
+func offerResume(filter string) string {
+	s, ok := loadSession()
+	if !ok {
+		return filter
+	}
+	fmt.Printf("\nThe last session ended abnormally, with filter %q.", s.Filter)
+	fmt.Printf(" Resume it? [y/N] ")
+	if r := readKey(); r != 'y' && r != 'Y' {
+		fmt.Println()
+		return filter
+	}
+	lg.Infof("Resuming session: %+v", s)
+	sortBy, showAll = s.SortBy, s.ShowAll
+	cur.Filter = s.Filter
+	if len(s.Uuid) == 0 {
+		return s.Filter
+	}
+
+	tasks, err := getTasks(s.Filter)
+	if err != nil {
+		lg.Fatalf("While getting tasks for filter %q: %v", s.Filter, err)
+	}
+	for i, tk := range tasks {
+		if tk.Uuid == s.Uuid {
+			reviewLoop(tasks, i)
+			break
+		}
+	}
+	return s.Filter
+}
+
+// loadPositions returns the uuid of the task each filter's review was left
+// off at.
+func loadPositions() map[string]string {
+	pos := make(map[string]string)
+	data, err := ioutil.ReadFile(*positionsPath)
+	if err != nil {
+		return pos
+	}
+	if err := json.Unmarshal(data, &pos); err != nil {
+		lg.Errorf("While parsing positions %q: %v", *positionsPath, err)
+	}
+	return pos
+}
+
+// savePosition remembers where the filter's review was left off. An empty
+// uuid forgets it, once the review is done.
+func savePosition(filter, uuid string) {
+	pos := loadPositions()
+	if len(uuid) == 0 {
+		delete(pos, filter)
+	} else {
+		pos[filter] = uuid
+	}
+	data, err := json.Marshal(pos)
+	if err != nil {
+		lg.Errorf("While marshalling positions: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(*positionsPath, data, 0600); err != nil {
+		lg.Errorf("While saving positions to %q: %v", *positionsPath, err)
+	}
+}
+
+// resumePosition offers to continue the filter's review from where it was
+// left off, returning the index to start at.
+func resumePosition(filter string, tasks []task) int {
+	uuid, ok := loadPositions()[filter]
+	if !ok {
+		return 0
+	}
+	for i, tk := range tasks {
+		if tk.Uuid != uuid || i == 0 {
+			continue
+		}
+		fmt.Printf("\nContinue from task %d: %s? [Y/n] ", i, tk.Description)
+		r := readKey()
+		fmt.Println()
+		if r == 'n' || r == 'N' {
+			return 0
+		}
+		return i
+	}
+	return 0
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var settingsPath = flag.String("settings", os.Getenv("HOME")+"/.taskreview.json",
+	"Path of the JSON settings file.")
+
+// state is a color, or more generally any workflow state, a task can be in.
+// It's stored as a tag on the task.
+type state struct {
+	Name   string `json:"name"`
+	Key    string `json:"key,omitempty"`
+	Badge  string `json:"badge,omitempty"`
+	Fg     string `json:"fg,omitempty"`
+	Bg     string `json:"bg,omitempty"`
+	Weight int    `json:"weight,omitempty"`
+	// Limit is the most pending tasks one person can have in this state.
+	Limit       int    `json:"limit,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// settings holds everything configurable beyond the commandline flags.
+type settings struct {
+	// States are listed in the order they sort in.
+	States []state `json:"states"`
+	// FixState is given to tasks without any state, and to new tasks.
+	FixState string `json:"fix_state"`
+	// Escalations are checked in order, at the start of each session.
+	Escalations []escalation `json:"escalations,omitempty"`
+	// Triage rules are run by the fix action, over the listed tasks.
+	Triage []triageRule `json:"triage,omitempty"`
+	// Macros chain task actions under a single key of the task view.
+	Macros []macro `json:"macros,omitempty"`
+	// Advance overrides how far to move in the review, after a task action
+	// changes the task. For e.g., {"color": 1} to move on after a color edit.
+	Advance map[string]int `json:"advance,omitempty"`
+	// Columns of the task list, in order.
+	Columns []column `json:"columns,omitempty"`
+	// RelativeTimes shows when a task was started, completed and modified as
+	// how long ago it was, instead of as a date.
+	RelativeTimes bool `json:"relative_times,omitempty"`
+	// Icons shows the review badges as unicode symbols, and the color as a
+	// colored dot. Symbols overrides the symbol for a badge letter.
+	Icons   bool              `json:"icons,omitempty"`
+	Symbols map[string]string `json:"symbols,omitempty"`
+	// StrikeCompleted strikes through completed tasks in the list, on top of
+	// dimming them.
+	StrikeCompleted bool `json:"strike_completed,omitempty"`
+	// IdleAfter is how long a task can stay started before the list flags it,
+	// and the review asks whether it's still being worked on.
+	IdleAfter duration `json:"idle_after,omitempty"`
+	// TrackPrompt offers to start tracking urgent tasks as they're opened.
+	TrackPrompt trackPromptSettings `json:"track_prompt"`
+	// Archive sets up archiving of reviewed, completed tasks.
+	Archive archiveSettings `json:"archive"`
+}
+
+var cfg = settings{
+	States: []state{
+		{Name: "red", Key: "r", Fg: "white", Bg: "red", Weight: 3,
+			Description: "Urgent. Work on it now."},
+		{Name: "blue", Key: "b", Fg: "white", Bg: "blue", Weight: 2,
+			Description: "Important. Work on it this week."},
+		{Name: "green", Key: "g", Fg: "black", Bg: "green", Weight: 1,
+			Description: "Normal. Work on it when there's time."},
+	},
+	FixState:  "green",
+	Columns:   append([]column(nil), defaultColumns...),
+	IdleAfter: duration(4 * time.Hour),
+}
+
+// loadSettings reads the settings file, if any, over the defaults.
+func loadSettings() {
+	data, err := ioutil.ReadFile(*settingsPath)
+	if os.IsNotExist(err) {
+		lg.Infof("No settings file at %q. Using defaults.", *settingsPath)
+		return
+	}
+	if err != nil {
+		lg.Fatalf("While reading settings %q: %v", *settingsPath, err)
+	}
+	// Unmarshalling over the default slices would reuse their elements, and
+	// have the states and columns set in the file inherit the defaults' fields.
+	states := cfg.States
+	cfg.States, cfg.Columns = nil, nil
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		lg.Fatalf("While parsing settings %q: %v", *settingsPath, err)
+	}
+	if cfg.States == nil {
+		cfg.States = states
+	}
+	if len(cfg.Columns) == 0 {
+		cfg.Columns = append([]column(nil), defaultColumns...)
+	}
+	if len(cfg.States) == 0 {
+		lg.Fatalf("Settings %q must define at least one state.", *settingsPath)
+	}
+	if _, ok := findState(cfg.FixState); !ok {
+		lg.Fatalf("fix_state %q isn't one of the states in %q.", cfg.FixState, *settingsPath)
+	}
+	for _, e := range cfg.Escalations {
+		if !isStateTag(e.From) || !isStateTag(e.To) {
+			lg.Fatalf("Escalation %+v in %q refers to an unknown state.", e, *settingsPath)
+		}
+	}
+	for _, r := range cfg.Triage {
+		if len(r.Set.Color) > 0 && !isStateTag(r.Set.Color) {
+			lg.Fatalf("Triage rule %q in %q sets an unknown state: %q",
+				r.Name, *settingsPath, r.Set.Color)
+		}
+	}
+	if cfg.Archive.Auto && len(cfg.Archive.Project) == 0 {
+		lg.Fatalf("archive in %q needs a project to archive under.", *settingsPath)
+	}
+	for _, c := range cfg.Columns {
+		if _, ok := columnPrinters[c.Name]; !ok {
+			lg.Fatalf("Unknown column %q in %q.", c.Name, *settingsPath)
+		}
+	}
+	lg.Infof("Loaded settings from %q", *settingsPath)
+}
+
+// findState returns the state with the given name, and its sort order.
+func findState(name string) (int, bool) {
+	for i, s := range cfg.States {
+		if s.Name == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func isStateTag(t string) bool {
+	_, ok := findState(t)
+	return ok
+}
+
+var fgColors = map[string]color.Attribute{
+	"black": color.FgBlack, "red": color.FgRed, "green": color.FgGreen,
+	"yellow": color.FgYellow, "blue": color.FgBlue, "magenta": color.FgMagenta,
+	"cyan": color.FgCyan, "white": color.FgWhite,
+}
+
+var bgColors = map[string]color.Attribute{
+	"black": color.BgBlack, "red": color.BgRed, "green": color.BgGreen,
+	"yellow": color.BgYellow, "blue": color.BgBlue, "magenta": color.BgMagenta,
+	"cyan": color.BgCyan, "white": color.BgWhite,
+}
+
+// stateColor returns the color to render the named state in. Unknown
+// states render white on black.
+func stateColor(name string) *color.Color {
+	fg, bg := color.FgWhite, color.BgBlack
+	if i, ok := findState(name); ok {
+		if a, ok := fgColors[cfg.States[i].Fg]; ok {
+			fg = a
+		}
+		if a, ok := bgColors[cfg.States[i].Bg]; ok {
+			bg = a
+		}
+	}
+	return color.New(bg, fg)
+}
+
+// stateDot returns the color to render the named state's dot in, when
+// showing icons. It's the background color of the state.
+func stateDot(name string) *color.Color {
+	fg := color.FgWhite
+	if i, ok := findState(name); ok {
+		if a, ok := fgColors[cfg.States[i].Bg]; ok {
+			fg = a
+		}
+	}
+	return color.New(fg)
+}
+
+// stateBadge returns the label to show for the named state.
+func stateBadge(name string) string {
+	if i, ok := findState(name); ok && len(cfg.States[i].Badge) > 0 {
+		return cfg.States[i].Badge
+	}
+	return name
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	statsFlags = commandFlags("stats", filterArg)
+	statsJSON  = statsFlags.Bool("json", false, "Print the counts as JSON.")
+)
+
+// stats are the counts printed by the stats command, for the pending tasks
+// matching a filter.
+type stats struct {
+	Pending    int `json:"pending"`
+	Unreviewed int `json:"unreviewed"`
+	Disputed   int `json:"disputed"`
+	Overdue    int `json:"overdue"`
+}
+
+func countStats(tasks []task) stats {
+	var s stats
+	now := time.Now()
+	for _, tk := range tasks {
+		s.Pending++
+		if !tk.isReviewed() {
+			s.Unreviewed++
+		}
+		if tk.isDisputed() {
+			s.Disputed++
+		}
+		if _, overdue := tk.dueLabel(now); overdue {
+			s.Overdue++
+		}
+	}
+	return s
+}
+
+// runStats prints the counts on one line, or as JSON, to fit in a shell
+// prompt or status bar.
+func runStats() error {
+	loadSettings()
+	filter := commandFilter(statsFlags)
+	if _, window := splitWindow(filter); len(window) > 0 {
+		return errors.Errorf("stats counts pending tasks, so filter %q can't have a window", filter)
+	}
+	tasks, err := getTasks(filter)
+	if err != nil {
+		return errors.Wrapf(err, "while getting tasks for filter %q", filter)
+	}
+	s := countStats(tasks)
+	if *statsJSON {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return errors.Wrapf(err, "while marshalling stats")
+		}
+		fmt.Println(string(data))
+		return s.exitErr()
+	}
+	fmt.Printf("pending %d, unreviewed %d, disputed %d, overdue %d\n",
+		s.Pending, s.Unreviewed, s.Disputed, s.Overdue)
+	return s.exitErr()
+}
+
+// exitErr returns errUnreviewed if any of the tasks need a review.
+func (s stats) exitErr() error {
+	if s.Unreviewed > 0 {
+		return errUnreviewed
+	}
+	return nil
+}
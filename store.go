@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// store holds every task, exported once per session. The filters the shell
+// builds are applied to it locally, so taskwarrior only gets run for writes,
+// explicit refreshes, and filters we can't evaluate ourselves.
+type store struct {
+	tasks []*task // In export order.
+	// byUuid indexes the same tasks as the slice, for lookups.
+	byUuid map[string]*task
+	loaded time.Time
+	// fetched is when each task was last fetched on its own, if it was
+	// after the store was loaded.
+	fetched map[string]time.Time
+}
+
+var (
+	lazy = flag.Bool("lazy", false,
+		"Keep tasks in memory without their annotations, and fetch those when a task is opened.")
+	staleAfter = flag.Duration("stale", time.Minute,
+		"Before writing a task, check taskwarrior for changes made elsewhere, if our copy is older than this.")
+	db store
+)
+
+// load exports all the tasks afresh.
+func (s *store) load() error {
+	var tasks []task
+	err := runTaskStream(func(r io.Reader) error {
+		var err error
+		// Deleted tasks are never shown, so aren't worth keeping.
+		tasks, err = decodeTasks(r, func(t task) bool { return t.Status != "deleted" })
+		return err
+	}, "export")
+	if err != nil {
+		return errors.Wrapf(err, "while exporting all tasks")
+	}
+	if *lazy {
+		for i := range tasks {
+			tasks[i].slim()
+		}
+	}
+	remember(tasks)
+	s.tasks = make([]*task, len(tasks))
+	s.byUuid = make(map[string]*task, len(tasks))
+	for i := range tasks {
+		s.tasks[i] = &tasks[i]
+		s.byUuid[tasks[i].Uuid] = &tasks[i]
+	}
+	s.loaded = time.Now()
+	s.fetched = make(map[string]time.Time)
+	computeDepends(s.tasks)
+	writeCompletionCache(s.tasks)
+	// Tasks may have been started or stopped elsewhere.
+	forgetTracked()
+	lg.Infof("Loaded %d tasks into the store.", len(tasks))
+	return nil
+}
+
+// decodeTasks decodes an export one task at a time, keeping those which
+// keep returns true for.
+func decodeTasks(r io.Reader, keep func(task) bool) ([]task, error) {
+	defer timed("decode", time.Now())
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil { // [
+		return nil, errors.Wrapf(err, "while reading export")
+	}
+	var tasks []task
+	for dec.More() {
+		var t task
+		if err := dec.Decode(&t); err != nil {
+			return nil, errors.Wrapf(err, "while decoding export")
+		}
+		if keep(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	if _, err := dec.Token(); err != nil { // ]
+		return nil, errors.Wrapf(err, "while reading export")
+	}
+	return tasks, nil
+}
+
+// put updates the stored copy of the task, after it was written or
+// fetched again.
+func (s *store) put(t task) {
+	if s.loaded.IsZero() {
+		return
+	}
+	t = t.clone()
+	s.fetched[t.Uuid] = time.Now()
+	if p, ok := s.byUuid[t.Uuid]; ok {
+		// Only changes to the status or dependencies can change what's
+		// blocked.
+		changed := p.Status != t.Status || strings.Join(p.Depends, ",") != strings.Join(t.Depends, ",")
+		*p = t
+		if changed {
+			computeDepends(s.tasks)
+		}
+		return
+	}
+	s.tasks = append(s.tasks, &t)
+	s.byUuid[t.Uuid] = &t
+	computeDepends(s.tasks)
+}
+
+// fresh returns the stored copy of the task, if it was fetched from
+// taskwarrior recently enough to trust.
+func (s *store) fresh(uuid string) (task, bool) {
+	at, ok := s.fetched[uuid]
+	if !ok {
+		at = s.loaded
+	}
+	if s.loaded.IsZero() || time.Since(at) > *staleAfter {
+		return task{}, false
+	}
+	return s.get(uuid)
+}
+
+// get returns the stored copy of the task.
+func (s *store) get(uuid string) (task, bool) {
+	if p, ok := s.byUuid[uuid]; ok {
+		return p.clone(), true
+	}
+	return task{}, false
+}
+
+// cachedTask returns the task from the store, which writes keep up to date.
+// Only tasks missing from it are fetched from taskwarrior.
+func cachedTask(uuid string) task {
+	if t, ok := db.get(uuid); ok {
+		return t
+	}
+	return getTask(uuid)
+}
+
+// match returns the stored tasks matching the filter args. It returns false
+// if the filter uses syntax we don't evaluate locally, or if the store isn't
+// loaded. Only the review session loads it, so that one-shot commands export
+// just the tasks their filter matches.
+func (s *store) match(args []string) ([]task, bool) {
+	return s.matchUntil(args, nil)
+}
+
+// matchUntil is match, giving up with false once cancel is closed.
+func (s *store) matchUntil(args []string, cancel <-chan struct{}) ([]task, bool) {
+	if s.loaded.IsZero() {
+		return nil, false
+	}
+	if len(args) == 1 {
+		if t, ok := s.get(args[0]); ok {
+			return []task{t}, true
+		}
+	}
+	p := &filterParser{args: args, ok: true}
+	fn := p.or()
+	if !p.ok || p.pos < len(p.args) {
+		lg.Debugf("Filter %q goes to taskwarrior.", args)
+		return nil, false
+	}
+	var res []task
+	for i, t := range s.tasks {
+		if i%1000 == 0 && cancel != nil {
+			select {
+			case <-cancel:
+				return nil, false
+			default:
+			}
+		}
+		if fn(*t) {
+			res = append(res, t.clone())
+		}
+	}
+	return res, true
+}
+
+// slim drops the annotations of the task, keeping their count for the list.
+func (t *task) slim() {
+	t.partial, t.notes = true, len(t.Annotations)
+	t.Annotations = nil
+}
+
+// noteCount is the number of annotations on the task, even if partial.
+func (t task) noteCount() int {
+	if t.partial {
+		return t.notes
+	}
+	return len(t.Annotations)
+}
+
+// full returns the task with all its fields, fetching it if it's partial.
+func (t task) full() task {
+	if !t.partial {
+		return t
+	}
+	return getTask(t.Uuid)
+}
+
+// clone copies the task, so that edits which modify its slices in place
+// don't reach the stored copy.
+func (t task) clone() task {
+	t.Tags = append([]string(nil), t.Tags...)
+	t.Annotations = append([]annotation(nil), t.Annotations...)
+	t.Depends = append(dependsList(nil), t.Depends...)
+	return t
+}
+
+var (
+	uuidPrefixExp = regexp.MustCompile("^[0-9a-f]{8}(-[0-9a-f-]*)?$")
+	virtualTagExp = regexp.MustCompile("^[A-Z]+$")
+	idListExp     = regexp.MustCompile("^[0-9,-]+$")
+)
+
+// filterParser compiles the part of the taskwarrior filter syntax that the
+// shell builds: tags, projects, uuids, ids and words, combined with
+// parentheses, "and" and "or". Anything else clears ok.
+type filterParser struct {
+	args []string
+	pos  int
+	ok   bool
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.args) {
+		return p.args[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) or() func(task) bool {
+	left := p.and()
+	for p.peek() == "or" {
+		p.pos++
+		l, r := left, p.and()
+		left = func(t task) bool { return l(t) || r(t) }
+	}
+	return left
+}
+
+func (p *filterParser) and() func(task) bool {
+	left := func(t task) bool { return true }
+	for p.pos < len(p.args) && p.peek() != "or" && p.peek() != ")" {
+		if p.peek() == "and" {
+			p.pos++
+		}
+		l, r := left, p.term()
+		left = func(t task) bool { return l(t) && r(t) }
+	}
+	return left
+}
+
+func (p *filterParser) term() func(task) bool {
+	arg := p.peek()
+	p.pos++
+	switch {
+	case arg == "(":
+		fn := p.or()
+		if p.peek() != ")" {
+			p.ok = false
+		}
+		p.pos++
+		return fn
+	case len(arg) > 1 && (arg[0] == '+' || arg[0] == '-'):
+		tag, want := arg[1:], arg[0] == '+'
+		if virtualTagExp.MatchString(tag) {
+			p.ok = false
+		}
+		return func(t task) bool { return t.hasTag(tag) == want }
+	case strings.HasPrefix(arg, "project:"):
+		proj := arg[len("project:"):]
+		return func(t task) bool {
+			if len(proj) == 0 {
+				return len(t.Project) == 0
+			}
+			return t.Project == proj || strings.HasPrefix(t.Project, proj+".")
+		}
+	case uuidPrefixExp.MatchString(arg):
+		return func(t task) bool { return strings.HasPrefix(t.Uuid, arg) }
+	case idListExp.MatchString(arg):
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			// Ranges and lists of ids.
+			p.ok = false
+		}
+		return func(t task) bool { return t.Id == id }
+	case arg == "xor" || arg == "and" || arg == "or" || arg == ")" ||
+		strings.ContainsAny(arg, ":=<>/\\~") || len(arg) == 0:
+		p.ok = false
+		return func(t task) bool { return false }
+	}
+	// A plain word matches the description or the annotations, which only
+	// taskwarrior has under -lazy.
+	if *lazy {
+		p.ok = false
+	}
+	return func(t task) bool {
+		if strings.Contains(t.Description, arg) {
+			return true
+		}
+		for _, a := range t.Annotations {
+			if strings.Contains(a.Description, arg) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTarget is how long opening a database of benchTasks tasks should take,
+// as the README promises.
+const (
+	benchTasks = 20000
+	openTarget = 2 * time.Second
+)
+
+// benchExport returns an export of n tasks, spread over projects, users,
+// colors and statuses, with a few annotations each.
+func benchExport(b *testing.B, n int) []byte {
+	now := time.Now().UTC()
+	colors := []string{"red", "blue", "green", ""}
+	tasks := make([]task, n)
+	for i := range tasks {
+		tk := task{
+			Uuid:        fmt.Sprintf("%08x-0000-4000-8000-%012x", i, i),
+			Id:          i + 1,
+			Description: fmt.Sprintf("Task %d with a description of typical length, about the %d bug", i, i%97),
+			Project:     fmt.Sprintf("project%d.sub%d", i%20, i%3),
+			Status:      "pending",
+			Created:     now.Add(-time.Duration(i) * time.Hour).Format(stamp),
+			Modified:    now.Add(-time.Duration(i) * time.Minute).Format(stamp),
+			Urgency:     float64(i%150) / 10,
+			Tags:        []string{fmt.Sprintf("@user%d", i%15), fmt.Sprintf("tag%d", i%40)},
+		}
+		if c := colors[i%len(colors)]; len(c) > 0 {
+			tk.Tags = append(tk.Tags, c)
+		}
+		if i%5 == 0 {
+			tk.Due = now.Add(time.Duration(i%30-10) * 24 * time.Hour).Format(stamp)
+		}
+		if i%3 == 0 {
+			tk.Status, tk.Completed = "completed", now.Add(-time.Duration(i)*time.Minute).Format(stamp)
+		}
+		for j := 0; j < i%4; j++ {
+			tk.Annotations = append(tk.Annotations, annotation{
+				Entry: tk.Created, Description: fmt.Sprintf("Note %d on task %d", j, i)})
+		}
+		tasks[i] = tk
+	}
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func BenchmarkDecodeTasks(b *testing.B) {
+	data := benchExport(b, benchTasks)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tasks, err := decodeTasks(bytes.NewReader(data), func(task) bool { return true })
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(tasks) != benchTasks {
+			b.Fatalf("Decoded %d tasks, want %d", len(tasks), benchTasks)
+		}
+	}
+}
+
+func BenchmarkSortTasks(b *testing.B) {
+	tasks, err := decodeTasks(bytes.NewReader(benchExport(b, benchTasks)),
+		func(task) bool { return true })
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, name := range []string{"urgency", "date", "color"} {
+		b.Run(name, func(b *testing.B) {
+			defer func(orig int) { sortBy = orig }(sortBy)
+			sortBy = exportSorts[name]
+			sorted := make([]task, len(tasks))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				copy(sorted, tasks)
+				sortTasks(sorted)
+			}
+		})
+	}
+}
+
+// BenchmarkGetTasks opens the database as a review does: a full export into
+// the store, and the list's filter over it. It reports how much of openTarget
+// each run took, as timings vary too much between machines to fail on.
+func BenchmarkGetTasks(b *testing.B) {
+	dir := fakeTask(b)
+	if err := ioutil.WriteFile(filepath.Join(dir, "export.json"), benchExport(b, benchTasks), 0644); err != nil {
+		b.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		db = store{}
+		if err := db.load(); err != nil {
+			b.Fatal(err)
+		}
+		tasks, err := getTasks("project:project3")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(tasks) == 0 {
+			b.Fatal("No tasks matched.")
+		}
+	}
+	per := time.Since(start) / time.Duration(b.N)
+	b.ReportMetric(100*per.Seconds()/openTarget.Seconds(), "%target")
+	if per > openTarget {
+		b.Logf("Opening %d tasks took %v, over the target of %v.", benchTasks, per, openTarget)
+	}
+}
+
+func TestLazyPlainWordsGoToTaskwarrior(t *testing.T) {
+	defer func(l bool) { *lazy = l }(*lazy)
+	*lazy = true
+	tk := task{Uuid: "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", Description: "call bob",
+		Status: "pending", Annotations: []annotation{{Description: "about the invoice"}}}
+	tk.slim()
+	db.tasks = []*task{&tk}
+	db.byUuid = map[string]*task{tk.Uuid: &tk}
+	db.loaded, db.fetched = time.Now(), make(map[string]time.Time)
+	defer func() { db = store{} }()
+
+	if _, ok := db.match([]string{"invoice"}); ok {
+		t.Error("Matched a plain word locally, without the annotations.")
+	}
+	if tasks, ok := db.match([]string{"project:"}); !ok || len(tasks) != 1 {
+		t.Errorf("Got %d tasks, matched %v. Want the task matched locally.", len(tasks), ok)
+	}
+	remember([]task{tk})
+	if _, ok := snapshot(tk.Uuid, tk.Modified); ok {
+		t.Error("Remembered a task without its annotations.")
+	}
+}
@@ -1,12 +1,12 @@
 package main
 
 import (
-	"bufio"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
-	"log"
-	"os"
-	"os/exec"
+	"hash/fnv"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,71 +14,169 @@ import (
 	"github.com/pkg/errors"
 )
 
+type annotation struct {
+	Entry       string `json:"entry,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
 type task struct {
 	Completed   string   `json:"end,omitempty"`
 	Created     string   `json:"entry,omitempty"`
 	Description string   `json:"description,omitempty"`
+	Due         string   `json:"due,omitempty"`
 	Modified    string   `json:"modified,omitempty"`
 	Project     string   `json:"project,omitempty"`
 	Status      string   `json:"status,omitempty"`
+	Until       string   `json:"until,omitempty"`
+	Start       string   `json:"start,omitempty"`
 	Tags        []string `json:"tags,omitempty"`
+	Id          int      `json:"id,omitempty"`
 	Uuid        string   `json:"uuid,omitempty"`
 	Xid         string   `json:"xid,omitempty"`
-	Reviewed    string   `json:"reviewed,omitempty"`
-	Urgency     float64  `json:"urgency,omitempty"`
+	ReviewedAt  string   `json:"reviewed_at,omitempty"`
+	ReviewedBy  string   `json:"reviewed_by,omitempty"`
+	// Reviewed is only read, to migrate reviews written by older versions.
+	Reviewed string  `json:"reviewed,omitempty"`
+	Urgency  float64 `json:"urgency,omitempty"`
+	// Pomodoros is the number of pomodoros spent on the task.
+	Pomodoros int `json:"pomodoros,omitempty"`
+	// Client is who time tracked on the task is billed to, if it's billable.
+	Client string `json:"client,omitempty"`
+	// Estimate is how long the task is expected to take.
+	Estimate string `json:"estimate,omitempty"`
+	// Depends are the uuids of the tasks this one waits on.
+	Depends dependsList `json:"depends,omitempty"`
+
+	Annotations []annotation `json:"annotations,omitempty"`
+
+	// partial is set on tasks loaded without their annotations, which then
+	// only have their count in notes.
+	partial bool
+	notes   int
 }
 
-type ByDefined []task
+// sortKey holds what tasks get sorted by, worked out once per task rather
+// than on every comparison, which adds up on big lists.
+type sortKey struct {
+	urgency float64
+	at      time.Time
+	color   int
+	shuffle uint64
+}
+
+type byDefined struct {
+	tasks []task
+	keys  []sortKey
+}
 
-func (b ByDefined) Len() int          { return len(b) }
-func (b ByDefined) Swap(i int, j int) { b[i], b[j] = b[j], b[i] }
-func (b ByDefined) Less(i int, j int) bool {
+func (b byDefined) Len() int { return len(b.tasks) }
+func (b byDefined) Swap(i int, j int) {
+	b.tasks[i], b.tasks[j] = b.tasks[j], b.tasks[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+func (b byDefined) Less(i int, j int) bool {
+	ki, kj := b.keys[i], b.keys[j]
 	if sortBy == URGENCY {
-		return b[i].Urgency > b[j].Urgency
+		return ki.urgency > kj.urgency
 
 	} else if sortBy == DATE {
-		t1 := b[i].sortTime()
-		t2 := b[j].sortTime()
-		return t2.Before(t1)
+		return kj.at.Before(ki.at)
 	} else if sortBy == COLOR {
-		t1 := b[i].sortColor()
-		t2 := b[j].sortColor()
-		return t1 < t2
+		return ki.color < kj.color
+	} else if sortBy == RANDOM {
+		return ki.shuffle < kj.shuffle
 	}
 
-	log.Fatalf("Unhandled sortBy case for: %v", sortBy)
+	lg.Fatalf("Unhandled sortBy case for: %v", sortBy)
 	return true
 }
 
-func (tk task) sortTime() time.Time {
-	ts := tk.Completed
-	if len(ts) == 0 {
-		ts = tk.Created
+// sortTasks sorts the tasks in place, as picked by sortBy.
+func sortTasks(tasks []task) {
+	keys := make([]sortKey, len(tasks))
+	for i, tk := range tasks {
+		keys[i] = sortKey{tk.Urgency, tk.sortTime(), tk.sortColor(), tk.shuffleKey()}
 	}
+	sort.Sort(byDefined{tasks, keys})
+}
+
+// parseStamp parses a taskwarrior timestamp. Some imported tasks have
+// them missing, or malformed; those come back false.
+func parseStamp(ts string) (time.Time, bool) {
 	t, err := time.Parse(stamp, ts)
-	if err != nil {
-		log.Fatalf("While trying to parse: %v. Got err: %v", ts, err)
+	return t, err == nil
+}
+
+func (tk task) entered() (time.Time, bool) { return parseStamp(tk.Created) }
+func (tk task) ended() (time.Time, bool)   { return parseStamp(tk.Completed) }
+
+// dueLabel describes when the task is due, relative to now, as in "due 3d",
+// "due today" or "overdue 5d". It returns true if the task is overdue.
+func (tk task) dueLabel(now time.Time) (string, bool) {
+	due, ok := parseStamp(tk.Due)
+	if !ok || len(tk.Completed) > 0 {
+		return "", false
+	}
+	day := func(t time.Time) time.Time {
+		y, m, d := t.Local().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+	// Rounding absorbs the odd 23 or 25 hour day around DST changes.
+	days := int(math.Round(day(due).Sub(day(now)).Hours() / 24))
+	switch {
+	case days == 0 && due.Before(now):
+		return "overdue", true
+	case days == 0:
+		return "due today", false
+	case days < 0:
+		return fmt.Sprintf("overdue %dd", -days), true
+	default:
+		return fmt.Sprintf("due %dd", days), false
+	}
+}
+
+// badDates returns true if the entry, or a set end, can't be parsed.
+func (tk task) badDates() bool {
+	if _, ok := tk.entered(); !ok {
+		return true
+	}
+	if len(tk.Completed) > 0 {
+		if _, ok := tk.ended(); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sortTime returns when the task ended, or else when it was entered. Tasks
+// with unknown dates sort as the zero time.
+func (tk task) sortTime() time.Time {
+	if len(tk.Completed) > 0 {
+		t, _ := tk.ended()
+		return t
 	}
+	t, _ := tk.entered()
 	return t
 }
 
+// shuffleSeed fixes the random order for the session, so that it doesn't
+// change on every resort.
+var shuffleSeed = fmt.Sprint(time.Now().UnixNano())
+
+func (tk task) shuffleKey() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(shuffleSeed + tk.Uuid))
+	return h.Sum64()
+}
+
 func (tk task) sortColor() int {
-	c := tk.colorTag()
-	switch c {
-	case "red":
-		return 0
-	case "blue":
-		return 1
-	case "green":
-		return 2
-	default:
-		return -1
-	}
+	i, _ := findState(tk.colorTag())
+	return i
 }
 
 func (tk task) colorTag() string {
 	for _, t := range tk.Tags {
-		if t == "green" || t == "blue" || t == "red" {
+		if isStateTag(t) {
 			return t
 		}
 	}
@@ -94,38 +192,145 @@ func (tk task) userTag() string {
 	return ""
 }
 
-func (tk task) isReviewed() bool {
-	now := time.Now().UTC()
-	if len(tk.Completed) == 0 {
-		// Incomplete task. So, only update local version.
-		if len(tk.Reviewed) > 0 {
-			rev, err := time.Parse(stamp, tk.Reviewed)
-			if err == nil {
-				if now.Sub(rev) < 24*time.Hour {
-					return true
-				}
+// reviews parses the reviews of a pending task. The reviewed_by UDA holds
+// comma separated review tags, and reviewed_at the matching timestamps.
+// The Reviewed field written by older versions holds "rtag=timestamp"
+// entries, or a bare timestamp attributed to the current reviewer.
+func (tk task) reviews() map[string]time.Time {
+	res := make(map[string]time.Time)
+	if len(tk.Reviewed) > 0 {
+		for _, entry := range strings.Split(tk.Reviewed, ",") {
+			rtag, ts := *reviewTag, entry
+			if idx := strings.LastIndex(entry, "="); idx >= 0 {
+				rtag, ts = entry[:idx], entry[idx+1:]
 			}
+			tk.addReview(res, rtag, ts)
 		}
-	} else {
-		// Task has been completed. So, check for review tag.
-		for _, t := range tk.Tags {
-			if t == *reviewTag {
-				return true
-			}
+	}
+	if len(tk.ReviewedBy) > 0 {
+		by := strings.Split(tk.ReviewedBy, ",")
+		at := strings.Split(tk.ReviewedAt, ",")
+		if len(by) != len(at) {
+			lg.Errorf("Mismatched reviewed_by %q and reviewed_at %q for task %v",
+				tk.ReviewedBy, tk.ReviewedAt, tk.Uuid)
+		}
+		for i := 0; i < len(by) && i < len(at); i++ {
+			tk.addReview(res, by[i], at[i])
 		}
 	}
-	return false
+	return res
 }
 
-var kDisputed string = "disputed"
+func (tk task) addReview(revs map[string]time.Time, rtag, ts string) {
+	rev, err := time.Parse(stamp, ts)
+	if err != nil {
+		lg.Errorf("Unable to parse review %q by %q of task %v: %v", ts, rtag, tk.Uuid, err)
+		return
+	}
+	revs[rtag] = rev
+}
 
-func (tk task) isDisputed() bool {
+// setReviews is the inverse of reviews. It always writes the UDAs, which
+// migrates away from the older Reviewed field.
+func (tk *task) setReviews(revs map[string]time.Time) {
+	rtags := make([]string, 0, len(revs))
+	for rtag := range revs {
+		rtags = append(rtags, rtag)
+	}
+	sort.Strings(rtags)
+	at := make([]string, 0, len(revs))
+	for _, rtag := range rtags {
+		at = append(at, revs[rtag].Format(stamp))
+	}
+	tk.ReviewedBy = strings.Join(rtags, ",")
+	tk.ReviewedAt = strings.Join(at, ",")
+	tk.Reviewed = ""
+}
+
+// reviewedAt returns when rtag last reviewed the task. Completed tasks
+// carry the review tag, and reviews tagged by older versions have no
+// timestamp; those are taken to have happened on completion.
+func (tk task) reviewedAt(rtag string) (time.Time, bool) {
+	rev, ok := tk.reviews()[rtag]
+	if len(tk.Completed) == 0 {
+		return rev, ok
+	}
+	found := false
 	for _, t := range tk.Tags {
-		if t == kDisputed {
-			return true
+		if t == rtag {
+			found = true
 		}
 	}
-	return false
+	if !found {
+		return time.Time{}, false
+	}
+	if !ok {
+		rev, _ = time.Parse(stamp, tk.Completed)
+	}
+	return rev, true
+}
+
+// isReviewedBy returns true if rtag has reviewed the task within the
+// review window. A zero window means the review never expires.
+func (tk task) isReviewedBy(rtag string) bool {
+	rev, ok := tk.reviewedAt(rtag)
+	if !ok {
+		return false
+	}
+	window := *reviewWindow
+	if len(tk.Completed) > 0 {
+		window = *completedReviewWindow
+	}
+	return window == 0 || time.Now().UTC().Sub(rev) < window
+}
+
+func (tk task) isReviewed() bool {
+	return tk.isReviewedBy(*reviewTag)
+}
+
+// signoffs returns which of the tracked reviewers have and haven't reviewed
+// the task.
+func (tk task) signoffs() (done, pending []string) {
+	for _, rtag := range trackedReviewers() {
+		if tk.isReviewedBy(rtag) {
+			done = append(done, rtag)
+		} else {
+			pending = append(pending, rtag)
+		}
+	}
+	return done, pending
+}
+
+// Disputes move from disputed, to acknowledged by the assignee, to resolved.
+// The state is kept as a tag, so disputes can be filtered by state.
+var (
+	kDisputed     string = "disputed"
+	kAcknowledged string = "acknowledged"
+	kResolved     string = "resolved"
+)
+
+func (tk task) disputeState() string {
+	for _, t := range tk.Tags {
+		if t == kDisputed || t == kAcknowledged || t == kResolved {
+			return t
+		}
+	}
+	return ""
+}
+
+func (tk *task) setDisputeState(state string) {
+	tk.Tags = remove(tk.Tags, kDisputed)
+	tk.Tags = remove(tk.Tags, kAcknowledged)
+	tk.Tags = remove(tk.Tags, kResolved)
+	if len(state) > 0 {
+		tk.Tags = append(tk.Tags, state)
+	}
+}
+
+// isDisputed returns true if the task has an open dispute.
+func (tk task) isDisputed() bool {
+	s := tk.disputeState()
+	return s == kDisputed || s == kAcknowledged
 }
 
 func remove(t []string, something string) []string {
@@ -154,42 +359,185 @@ func toggle(t []string, something string) []string {
 	return f
 }
 
+// disputePrefix marks annotations which are part of a dispute thread.
+const disputePrefix = "dispute "
+
+func (tk *task) annotate(text string) {
+	tk.Annotations = append(tk.Annotations, annotation{
+		Entry:       time.Now().UTC().Format(stamp),
+		Description: text,
+	})
+}
+
+// disputeThread returns the annotations which form the dispute discussion.
+func (tk task) disputeThread() []annotation {
+	var thread []annotation
+	for _, a := range tk.Annotations {
+		if strings.HasPrefix(a.Description, disputePrefix) {
+			thread = append(thread, a)
+		}
+	}
+	return thread
+}
+
+// addToDispute records a comment from the current reviewer in the dispute
+// thread. It returns false if no comment was entered.
+func (tk *task) addToDispute(prompt string) bool {
+	text := readLine(prompt)
+	if len(text) == 0 {
+		return false
+	}
+	tk.annotate(disputePrefix + "@" + reviewerName(*reviewTag) + ": " + text)
+	return true
+}
+
+// toggleDisputed opens a dispute, or withdraws an open one.
 func (tk task) toggleDisputed() int {
-	tk.Tags = toggle(tk.Tags, kDisputed)
+	if tk.isDisputed() {
+		tk.setDisputeState("")
+	} else {
+		if !tk.addToDispute("Reason for dispute: ") {
+			return 0
+		}
+		tk.setDisputeState(kDisputed)
+	}
+	tk.doImport()
+	return 1
+}
+
+func (tk task) acknowledgeDispute() int {
+	if tk.disputeState() != kDisputed {
+		return 0
+	}
+	tk.annotate(disputePrefix + "@" + reviewerName(*reviewTag) + ": acknowledged")
+	tk.setDisputeState(kAcknowledged)
+	tk.doImport()
+	return 0
+}
+
+func (tk task) resolveDispute() int {
+	if !tk.isDisputed() {
+		return 0
+	}
+	if !tk.addToDispute("Resolution: ") {
+		return 0
+	}
+	tk.setDisputeState(kResolved)
 	tk.doImport()
 	return 1
 }
 
+func (tk task) replyToDispute() int {
+	if !tk.isDisputed() {
+		return 0
+	}
+	if tk.addToDispute("Reply: ") {
+		tk.doImport()
+	}
+	return 0
+}
+
+// confirm asks a yes or no question, if confirmations are turned on.
+func confirm(question string) bool {
+	if !*confirmActions {
+		return true
+	}
+	fmt.Printf("\n%s [y/N] ", question)
+	r := readKey()
+	fmt.Println()
+	return r == 'y' || r == 'Y'
+}
+
+// offerUndo gives a short window after a change, to put the task back the
+// way it was.
+func offerUndo(orig task, done string) {
+	if *undoWindow <= 0 || *dryRun {
+		return
+	}
+	fmt.Println()
+	color.New(color.BgYellow, color.FgBlack).Printf(
+		" %s. Press u within %v to undo. ", done, *undoWindow)
+	r, ok := readKeyTimeout(*undoWindow)
+	fmt.Println()
+	if !ok || r != 'u' {
+		return
+	}
+	// The task was just modified by us, so pick up its new mod time.
+	orig.Modified = cachedTask(orig.Uuid).Modified
+	orig.doImport()
+	lg.Infof("Undid %q on task %v", done, orig.Uuid)
+}
+
 func (t task) toggleDone() int {
 	if t.Status == "completed" {
-		t.Status = "pending"
-		t.Completed = ""
-	} else {
-		t.Status = "completed"
+		return t.reopen()
 	}
+	orig := t
+	if !confirm("Mark as done: " + t.Description + "?") {
+		return 0
+	}
+	t.Status = "completed"
+	t.Completed = time.Now().UTC().Format(stamp)
+	tracked := t.stopTracking()
+	t.doImport()
+	if tracked {
+		timew("stop")
+	}
+	offerUndo(orig, "Marked "+t.Status)
+	return 1
+}
+
+// reopen puts a completed task back to pending, noting why. It needs a
+// fresh review, since it wasn't done after all.
+func (t task) reopen() int {
+	if t.Status != "completed" {
+		notice = "Only completed tasks can be reopened."
+		return 0
+	}
+	orig := t
+	fmt.Println()
+	why := readLine("Why reopen it? ")
+	if len(why) == 0 {
+		return 0
+	}
+	t.Status = "pending"
+	t.Completed = ""
+	t.annotate("reopened: " + why)
+	t.resetReviews()
 	t.doImport()
+	offerUndo(orig, "Reopened")
 	return 1
 }
 
 func (t task) toggleReviewed() int {
+	revs := t.reviews()
 	if t.isReviewed() {
-		t.Reviewed = ""
+		delete(revs, *reviewTag)
+		t.setReviews(revs)
 		t.Tags = remove(t.Tags, *reviewTag)
+		t.doImport()
 		return 0
 	}
-	if len(t.Completed) == 0 {
-		t.Reviewed = time.Now().UTC().Format(stamp)
-	} else {
-		t.Tags = append(t.Tags, *reviewTag)
-	}
+	t.markReviewed()
 	t.doImport()
 	return 1
 }
 
+// markReviewed records a review of the task by us, now.
+func (t *task) markReviewed() {
+	revs := t.reviews()
+	revs[*reviewTag] = time.Now().UTC()
+	t.setReviews(revs)
+	if len(t.Completed) > 0 {
+		// Keep the tag around for completed tasks, so they can be filtered on.
+		t.Tags = append(remove(t.Tags, *reviewTag), *reviewTag)
+	}
+}
+
 func (t task) editTaskColor() int {
 	tags := t.Tags[:0]
 	for _, tag := range t.Tags {
-		if tag != "red" && tag != "green" && tag != "blue" {
+		if !isStateTag(tag) {
 			tags = append(tags, tag)
 		}
 	}
@@ -201,49 +549,124 @@ func (t task) editTaskColor() int {
 		return 0
 	}
 	t.Tags = tags
+	if !withinLimit(t) {
+		return 0
+	}
 	t.doImport()
 	return 0
 }
 
 func (t task) editDescription() int {
-	lineInputMode()
-	defer singleCharMode()
-
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("Enter description: ")
-	desc, err := reader.ReadString('\n')
-	if err != nil {
-		log.Fatal(err)
+	t.Description = readLine("Enter description: ")
+	if len(t.Description) == 0 {
+		return 0
 	}
-	t.Description = strings.Trim(desc, " \n")
-	if len(t.Description) > 0 {
-		t.doImport()
+	if u := t.mentionedUser(); len(u) > 0 {
+		fmt.Printf("Assign to %s? [y/N] ", u)
+		if r := readKey(); r == 'y' || r == 'Y' {
+			t.Tags = append(withoutUser(t.Tags), u)
+		}
+		fmt.Println()
 	}
+	t.doImport()
 	return 0
 }
 
+// mentionedUser returns the first known user mentioned as @name in the
+// description, who isn't already the assignee.
+func (t task) mentionedUser() string {
+	for _, m := range mentionExp.FindAllStringSubmatch(t.Description, -1) {
+		u := "@" + m[1]
+		if knownUsers[m[1]] && u != t.userTag() {
+			return u
+		}
+	}
+	return ""
+}
+
+// withoutUser returns the tags, minus the user tag.
+func withoutUser(tags []string) []string {
+	res := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if !strings.HasPrefix(t, "@") {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
 func (t task) editAssigned() int {
-	// We'll have to regenerate all the tags to modify the user tag.
-	// Filter out user tag from existing tags.
-	tags := t.Tags[:0]
-	for _, t := range t.Tags {
-		if t[0] != '@' {
-			tags = append(tags, t)
+	header := "Assign To"
+	var suggested string
+	if len(t.userTag()) == 0 {
+		// Unowned task, so offer the least loaded teammate.
+		if suggested = suggestAssignee(); len(suggested) > 0 {
+			header += " (Enter for " + suggested + ")"
 		}
 	}
 
-	ch := showAndGetResponse("Assign To", "user")
+	// We'll have to regenerate all the tags to modify the user tag.
+	// Filter out user tag from existing tags.
+	tags := withoutUser(t.Tags)
+	ch := showAndGetResponse(header, "user")
 	if a, ok := short.MapsTo(ch, "user"); ok {
 		// Now add user tag into all tags.
 		tags = append(tags, "@"+a)
+	} else if ch == 10 && len(suggested) > 0 {
+		tags = append(tags, suggested)
 	} else {
 		return 0
 	}
 	t.Tags = tags
+	if !withinLimit(t) {
+		return 0
+	}
 	t.doImport()
 	return 0
 }
 
+// resetReviews clears the sign offs of all reviewers.
+func (t *task) resetReviews() {
+	t.setReviews(map[string]time.Time{})
+	for _, rtag := range trackedReviewers() {
+		t.Tags = remove(t.Tags, rtag)
+	}
+}
+
+// delegate hands the task over to another user, leaving a note about the
+// handoff.
+func (t task) delegate() int {
+	ch := showAndGetResponse("Delegate To", "user")
+	a, ok := short.MapsTo(ch, "user")
+	if !ok {
+		return 0
+	}
+	from, to := t.userTag(), "@"+a
+	if from == to {
+		return 0
+	}
+	if len(from) == 0 {
+		from = "nobody"
+	}
+	t.Tags = append(withoutUser(t.Tags), to)
+	if !withinLimit(t) {
+		return 0
+	}
+	fmt.Println()
+	reason := readLine("Handoff note: ")
+
+	note := fmt.Sprintf("reassigned from %s to %s", from, to)
+	if len(reason) > 0 {
+		note += ": " + reason
+	}
+	t.annotate(note)
+	if *resetOnDelegate {
+		t.resetReviews()
+	}
+	t.doImport()
+	return 1
+}
+
 func (t task) editProject() int {
 	ch := showAndGetResponse("Project", "project")
 	if p, ok := short.MapsTo(ch, "project"); ok {
@@ -277,48 +700,188 @@ func (t task) editTags() int {
 }
 
 func (t task) deleteTask() int {
+	if !confirm("Delete: " + t.Description + "?") {
+		return 0
+	}
+	orig := t
 	t.Status = "deleted"
+	tracked := t.stopTracking()
 	t.doImport()
+	if tracked {
+		timew("stop")
+	}
+	offerUndo(orig, "Deleted")
 	return 1
 }
 
+// showDryRun shows what importing the task would change, instead of
+// importing it.
+func showDryRun(prev, t task, body []byte) {
+	fmt.Println()
+	boldBlue.Printf("[dry run] Would import: %s\n", body)
+	if len(prev.Uuid) == 0 {
+		fmt.Println("  New task.")
+	} else {
+		for _, f := range diffFields(prev, t) {
+			fmt.Printf("  %-12s %q -> %q\n", f.name, f.show(prev), f.show(t))
+		}
+	}
+	lg.Infof("Dry run import of task %v: %s", t.Uuid, body)
+	fmt.Println("Press any key to continue.")
+	readKey()
+}
+
+// colorPrefix marks annotations which record a change of color.
+const colorPrefix = "color "
+
+func colorName(c string) string {
+	if len(c) == 0 {
+		return "none"
+	}
+	return c
+}
+
+// recordColorChange annotates the task if its color differs from prev.
+func (t *task) recordColorChange(prev task) {
+	if from, to := prev.colorTag(), t.colorTag(); from != to {
+		t.annotate(colorPrefix + colorName(from) + " -> " + colorName(to))
+	}
+}
+
+// colorHistory returns the annotations which record color changes.
+func (t task) colorHistory() []annotation {
+	var hist []annotation
+	for _, a := range t.Annotations {
+		if strings.HasPrefix(a.Description, colorPrefix) {
+			hist = append(hist, a)
+		}
+	}
+	return hist
+}
+
+// imports counts the tasks imported during the session.
+var imports int
+
 // doImport iports the task.
 func (t task) doImport() {
+	_, t, ok := t.prepareImport()
+	if !ok {
+		return
+	}
+	if err := t.importJSON(); err != nil {
+		lg.Fatalf("%v", err)
+	}
+}
+
+// prepareImport returns the task as it currently is in taskwarrior, if it
+// exists, and the task to import over it. It returns false if there's
+// nothing to import.
+func (t task) prepareImport() (task, task, bool) {
+	var prev task
 	if len(t.Uuid) > 0 {
 		// If the task gets externally modified, we'd end up blindly overwriting those changes.
 		// So, run this check first for the mod time, and ensure that it's the same, before importing
-		// the modified task.
-		tasks, err := getTasks(t.Uuid)
-		if err != nil {
-			log.Fatalf("Error %v while retrieving tasks with UUID: %v", err, t.Uuid)
-			return
-		}
-		if len(tasks) > 1 {
-			log.Fatalf("Didn't expect to see more than 1 task with the same UUID: %v", t.Uuid)
+		// the modified task. A copy fetched recently enough is trusted, to save the export.
+		var found bool
+		if t.partial {
+			// Keep the annotations we never loaded, along with any new ones.
+			// If it changed since, the merge below takes care of that.
+			prev, found = getTask(t.Uuid), true
+			if prev.Modified == t.Modified {
+				t.Annotations = append(prev.Annotations, t.Annotations...)
+			}
+			t.partial = false
+		} else if prev, found = db.fresh(t.Uuid); !found {
+			var err error
+			if prev, err = exportTask(t.Uuid); err != nil {
+				lg.Errorf("While checking task %v before import: %v", t.Uuid, err)
+			}
+			found = err == nil
 		}
-		if len(tasks) == 1 {
-			prev := tasks[0]
+		if found {
 			if prev.Modified != t.Modified {
-				c := color.New(color.BgRed, color.FgWhite)
-				c.Printf(
-					"Task's mod time has changed [%q -> %q]. Please refresh before updating.",
-					t.Modified, prev.Modified)
-				fmt.Printf("\nPress enter to refresh.\n")
-				r := make([]byte, 1)
-				os.Stdin.Read(r)
-				return
+				// Reapply our edit on top of theirs, and only ask if that
+				// conflicts with what changed externally.
+				var merged task
+				base, ok := snapshot(t.Uuid, t.Modified)
+				if ok {
+					merged, ok = merge3(base, t, prev)
+				}
+				if ok {
+					lg.Infof("Merged external changes into task %v", t.Uuid)
+				} else if merged, ok = resolveConflict(t, prev); !ok {
+					return prev, t, false
+				}
+				t = merged
 			}
+			t.recordColorChange(prev)
 		}
 	}
+	if *dryRun {
+		body, _ := json.Marshal(t)
+		showDryRun(prev, t, body)
+		imports++
+		return prev, t, false
+	}
+	return prev, t, true
+}
+
+// newUuid returns a random, version 4 uuid.
+func newUuid() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrapf(err, "while generating a uuid")
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// written returns the task as taskwarrior stores it once imported: modified
+// now, with an end once done or deleted, and without an id unless pending.
+func (t task) written() task {
+	now := time.Now().UTC().Format(stamp)
+	t.Modified = now
+	if (t.Status == "completed" || t.Status == "deleted") && len(t.Completed) == 0 {
+		t.Completed = now
+	}
+	if t.Status != "pending" && t.Status != "waiting" {
+		t.Id = 0
+	}
+	return t
+}
 
+// importJSON runs task import on the task, as is.
+func (t task) importJSON() error {
+	isNew := len(t.Uuid) == 0
+	if isNew {
+		// Pick the uuid ourselves, to fetch just this task back.
+		var err error
+		if t.Uuid, err = newUuid(); err != nil {
+			return err
+		}
+	}
 	body, err := json.Marshal(t)
 	if err != nil {
-		log.Fatalf("While importing: %v", err)
+		return errors.Wrapf(err, "while marshalling task %v", t.Uuid)
 	}
 
-	cmd := fmt.Sprintf("echo -n %q | task import", body)
-	out, err := exec.Command("bash", "-c", cmd).Output()
+	out, err := runTaskInput(body, "import")
 	if err != nil {
-		log.Fatal(errors.Wrapf(err, "doImport [v] out:%q", cmd, out))
+		return errors.Wrapf(err, "doImport [%s] out:%q", body, out)
+	}
+	imports++
+	lg.Infof("Imported task %v: %q", t.Uuid, t.Description)
+	if isNew {
+		// Taskwarrior fills in the id, entry and urgency.
+		_, err := exportTask(t.Uuid)
+		return err
 	}
+	// Keep what we wrote, as taskwarrior would store it, rather than fetch
+	// it back. Once the -stale check fetches it again, its mod time won't
+	// match, so it's remembered as the base to merge only later edits onto.
+	w := t.written()
+	remember([]task{w})
+	db.put(w)
+	return nil
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTask puts a task binary on PATH which records its args, one run per
+// line, and the stdin of each import. Exports of one task print the task last
+// imported, and others the export.json in dir, or no tasks without one.
+func fakeTask(t testing.TB) (dir string) {
+	dir, err := ioutil.TempDir("", "taskreview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+echo "$@" >> "` + dir + `/args"
+if [ $# -eq 2 ] && [ ${#1} -eq 36 ] && [ "$2" = export ]; then
+	echo "["; cat "` + dir + `/stdin"; echo "]"
+	exit
+fi
+case " $* " in
+*" import "*) cat > "` + dir + `/stdin" ;;
+*" export "*) cat "` + dir + `/export.json" 2>/dev/null || echo "[]" ;;
+esac
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "task"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	cache := *completionCache
+	*completionCache = filepath.Join(dir, "completion")
+	t.Cleanup(func() {
+		os.Setenv("PATH", path)
+		*completionCache = cache
+		db = store{}
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+var adversarial = []string{
+	`Fix "quoted" and 'single quoted' text`,
+	`Run $(rm -rf /) and ${HOME}`,
+	"Run `whoami` in backticks",
+	`Escape \n and \\ and \" backslashes`,
+	"Spans\nseveral\nlines",
+	"Ünïcödé, 日本語 and emoji 🎉",
+	`A mix: "$(echo ` + "`id`" + `)" \; | & > /tmp/x`,
+}
+
+// imported returns the task fed to the last import.
+func imported(t *testing.T, dir string) task {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "stdin"))
+	if err != nil {
+		t.Fatalf("No import was run: %v", err)
+	}
+	var tk task
+	if err := json.Unmarshal(data, &tk); err != nil {
+		t.Fatalf("Import got invalid JSON %q: %v", data, err)
+	}
+	return tk
+}
+
+func TestImportNewTaskDescriptions(t *testing.T) {
+	dir := fakeTask(t)
+	want := make(map[string]bool)
+	for _, desc := range adversarial {
+		in := task{Description: desc, Status: "pending", Tags: []string{"@alice"}}
+		_, in, ok := in.prepareImport()
+		if !ok {
+			t.Fatalf("Nothing to import for %q", desc)
+		}
+		if err := in.importJSON(); err != nil {
+			t.Fatalf("Import of %q failed: %v", desc, err)
+		}
+		got := imported(t, dir)
+		if got.Description != desc {
+			t.Errorf("Imported description %q, want %q", got.Description, desc)
+		}
+		if len(got.Uuid) != 36 {
+			t.Errorf("Imported uuid %q, want one picked up front", got.Uuid)
+		}
+		want["import"], want[got.Uuid+" export"] = true, true
+	}
+	args, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The description only ever goes over stdin, never as an arg, and only the
+	// new task is fetched back.
+	for _, line := range strings.Split(strings.TrimSpace(string(args)), "\n") {
+		if !want[line] {
+			t.Errorf("Unexpected task args %q", line)
+		}
+	}
+}
+
+func TestImportExistingTaskDescriptions(t *testing.T) {
+	dir := fakeTask(t)
+	const uuid = "6f5c9b3e-1f2a-4b7c-9d8e-0a1b2c3d4e5f"
+	orig := task{Uuid: uuid, Description: "plain", Status: "pending",
+		Modified: time.Now().UTC().Format(stamp)}
+	db.tasks = []*task{&orig}
+	db.byUuid = map[string]*task{uuid: &orig}
+	db.loaded, db.fetched = time.Now(), make(map[string]time.Time)
+
+	for _, desc := range adversarial {
+		in := cachedTask(uuid)
+		in.Description = desc
+		_, in, ok := in.prepareImport()
+		if !ok {
+			t.Fatalf("Nothing to import for %q", desc)
+		}
+		if err := in.importJSON(); err != nil {
+			t.Fatalf("Import of %q failed: %v", desc, err)
+		}
+		if got := imported(t, dir); got.Description != desc || got.Uuid != uuid {
+			t.Errorf("Imported %q for %v, want %q for %v", got.Description, got.Uuid, desc, uuid)
+		}
+		if got := cachedTask(uuid).Description; got != desc {
+			t.Errorf("Stored description %q, want %q", got, desc)
+		}
+	}
+}
+
+func TestImportDoneLeavesPending(t *testing.T) {
+	fakeTask(t)
+	const uuid = "0d7e2c4a-5b6f-4a1e-8c9d-7e6f5a4b3c2d"
+	modified := time.Now().UTC().Add(-time.Hour).Format(stamp)
+	orig := task{Uuid: uuid, Id: 7, Description: "ship it", Status: "pending",
+		Created: modified, Modified: modified}
+	db.tasks = []*task{&orig}
+	db.byUuid = map[string]*task{uuid: &orig}
+	db.loaded, db.fetched = time.Now(), make(map[string]time.Time)
+
+	in := cachedTask(uuid)
+	in.Status = "completed"
+	_, in, ok := in.prepareImport()
+	if !ok {
+		t.Fatal("Nothing to import.")
+	}
+	if err := in.importJSON(); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err := getTasks(""); err != nil || len(pending) != 0 {
+		t.Errorf("Got %d pending tasks, err %v. Want none.", len(pending), err)
+	}
+	if done, err := getTasks("_end:all"); err != nil || len(done) != 1 {
+		t.Errorf("Got %d completed tasks, err %v. Want one.", len(done), err)
+	}
+	if got := cachedTask(uuid); got.Id != 0 || got.Modified == modified {
+		t.Errorf("Stored id %d and modified %v, want no id and a new mod time.", got.Id, got.Modified)
+	}
+}
+
+func TestMarkdownTableDescriptions(t *testing.T) {
+	rows := [][]string{{"description", "project"}}
+	for _, desc := range adversarial {
+		rows = append(rows, []string{desc, "ops|infra"})
+	}
+	var buf bytes.Buffer
+	if err := writeMarkdownTable(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// The header, the rule, and a line per task.
+	if want := len(rows) + 1; len(lines) != want {
+		t.Fatalf("Got %d lines, want %d:\n%s", len(lines), want, buf.String())
+	}
+	for _, line := range lines[2:] {
+		if !strings.HasSuffix(line, ` | ops\|infra |`) {
+			t.Errorf("Row %q doesn't end with its escaped project.", line)
+		}
+	}
+}
+
+func TestSnapshotsKeepLatest(t *testing.T) {
+	const uuid = "3c2b1a09-8f7e-4d6c-9b5a-4f3e2d1c0b9a"
+	defer delete(snapshots, uuid)
+	start := time.Now().UTC()
+	var versions []string
+	for i := 0; i < 5; i++ {
+		modified := start.Add(time.Duration(i) * time.Minute).Format(stamp)
+		versions = append(versions, modified)
+		remember([]task{{Uuid: uuid, Modified: modified}})
+	}
+	// Fetching the same version again doesn't push out the one before.
+	remember([]task{{Uuid: uuid, Modified: versions[4]}})
+	if n := len(snapshots[uuid]); n != keepSnapshots {
+		t.Errorf("Kept %d snapshots, want %d.", n, keepSnapshots)
+	}
+	for i, modified := range versions {
+		if _, ok := snapshot(uuid, modified); ok != (i >= 3) {
+			t.Errorf("Snapshot of version %d found: %v", i, ok)
+		}
+	}
+}
+
+func TestPickByPrefix(t *testing.T) {
+	k := &keymap{names: map[string][]string{"project": {
+		"q3-planning", "q4-planning", "ops", "ünïcode", "über", "alpha", "beta",
+		"gamma", "delta", "epsilon", "zeta", "eta", "theta"}}}
+	defer func() { replaying, recording = nil, nil }()
+	for _, c := range []struct{ keys, want string }{
+		{"q4", "q4-planning"},
+		{"o", "ops"},
+		{"ü\x7fün", "ünïcode"},
+		{"q2", "q4-planning"},
+		{"ü1", "über"},
+		{"\x1b", ""},
+	} {
+		replaying = []rune{}
+		for _, b := range []byte(c.keys) {
+			replaying = append(replaying, rune(b))
+		}
+		k.picked.name = ""
+		if r := k.pickByPrefix("project"); (r == pickedKey) != (len(c.want) > 0) || k.picked.name != c.want {
+			t.Errorf("Keys %q picked %q, want %q", c.keys, k.picked.name, c.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// term tracks the terminal mode, which is switched in-process, and only when
+// it changes.
+var term struct {
+	orig  *unix.Termios // As we found it, to restore for line input.
+	vmin  uint8
+	vtime uint8
+	raw   bool
+}
+
+func stdinFd() int { return int(os.Stdin.Fd()) }
+
+// setRaw puts the terminal into cbreak mode without echo, with reads
+// returning once vmin bytes are in, or after vtime tenths of a second.
+func setRaw(vmin, vtime uint8) {
+	if term.raw && term.vmin == vmin && term.vtime == vtime {
+		return
+	}
+	t, err := unix.IoctlGetTermios(stdinFd(), unix.TCGETS)
+	if err != nil {
+		// Not a terminal.
+		return
+	}
+	if !term.raw {
+		orig := *t
+		term.orig = &orig
+	}
+	t.Lflag &^= unix.ICANON | unix.ECHO
+	t.Cc[unix.VMIN], t.Cc[unix.VTIME] = vmin, vtime
+	if err := unix.IoctlSetTermios(stdinFd(), unix.TCSETS, t); err != nil {
+		lg.Errorf("While setting the terminal mode: %v", err)
+		return
+	}
+	term.raw, term.vmin, term.vtime = true, vmin, vtime
+}
+
+// singleCharMode has reads return on every key press, without echoing it.
+func singleCharMode() {
+	setRaw(1, 0)
+}
+
+// lineInputMode puts the terminal back the way it was, for reading lines.
+func lineInputMode() {
+	if !term.raw || term.orig == nil {
+		return
+	}
+	if err := unix.IoctlSetTermios(stdinFd(), unix.TCSETS, term.orig); err != nil {
+		lg.Errorf("While restoring the terminal mode: %v", err)
+		return
+	}
+	term.raw = false
+}
+
+// catchInterrupt has Ctrl-C come through as a key, instead of interrupting
+// us, while on.
+func catchInterrupt(on bool) {
+	t, err := unix.IoctlGetTermios(stdinFd(), unix.TCGETS)
+	if err != nil {
+		return
+	}
+	if on {
+		t.Lflag &^= unix.ISIG
+	} else {
+		t.Lflag |= unix.ISIG
+	}
+	if err := unix.IoctlSetTermios(stdinFd(), unix.TCSETS, t); err != nil {
+		lg.Errorf("While setting the terminal mode: %v", err)
+	}
+}
+
+// readKeyTimeout waits up to d for a key press. It returns false if none
+// came in time.
+func readKeyTimeout(d time.Duration) (rune, bool) {
+	// Have reads return after d, even without input. The terminal takes the
+	// time in tenths of a second, up to 255. Zero wouldn't wait at all, and
+	// callers waiting in a loop would spin.
+	tenths := d / (100 * time.Millisecond)
+	switch {
+	case tenths < 1:
+		tenths = 1
+	case tenths > 255:
+		tenths = 255
+	}
+	setRaw(0, uint8(tenths))
+	defer singleCharMode()
+
+	r := make([]byte, 1)
+	if n, _ := os.Stdin.Read(r); n == 0 {
+		return 0, false
+	}
+	return rune(r[0]), true
+}
+
+// termWidth returns the number of columns of the terminal, or 80 if that
+// can't be found.
+func termWidth() int {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 {
+		return 80
+	}
+	return int(ws.Col)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	timesheetFlags  = commandFlags("timesheet", "")
+	timesheetFrom   = timesheetFlags.String("from", "", "First day, as 2006-01-02. Defaults to a week ago, ending today.")
+	timesheetDays   = timesheetFlags.Int("days", 7, "Number of days to cover.")
+	timesheetFormat = timesheetFlags.String("format", "csv", "Format to write in: csv or markdown.")
+	timesheetOut    = timesheetFlags.String("o", "", "File to write to, instead of stdout.")
+)
+
+// timesheet is the time tracked per project, on each day.
+type timesheet struct {
+	days     []time.Time
+	projects []string
+	hours    map[string][]time.Duration
+}
+
+// rows returns the header, and a row per project with the hours on each day
+// and in total, followed by the totals of each day.
+func (ts timesheet) rows() [][]string {
+	header := []string{"project"}
+	for _, d := range ts.days {
+		header = append(header, d.Format("Mon 01-02"))
+	}
+	header = append(header, "total")
+	rows := [][]string{header}
+
+	totals := make([]time.Duration, len(ts.days)+1)
+	hours := func(d time.Duration) string { return fmt.Sprintf("%.2f", d.Hours()) }
+	for _, p := range ts.projects {
+		row := []string{p}
+		var sum time.Duration
+		for i, d := range ts.hours[p] {
+			row = append(row, hours(d))
+			sum += d
+			totals[i] += d
+		}
+		totals[len(ts.days)] += sum
+		rows = append(rows, append(row, hours(sum)))
+	}
+	row := []string{"total"}
+	for _, d := range totals {
+		row = append(row, hours(d))
+	}
+	return append(rows, row)
+}
+
+// timesheetWriters write the rows of the timesheet in each format.
+var timesheetWriters = map[string]func(w io.Writer, rows [][]string) error{
+	"csv": func(w io.Writer, rows [][]string) error {
+		cw := csv.NewWriter(w)
+		cw.WriteAll(rows)
+		return cw.Error()
+	},
+	"markdown": writeMarkdownTable,
+}
+
+// runTimesheet writes the time tracked in timewarrior on each day, per
+// project of the tasks it was tracked on, to submit as a timesheet.
+func runTimesheet() error {
+	loadSettings()
+	write, ok := timesheetWriters[*timesheetFormat]
+	if !ok {
+		return errors.Errorf("unknown format %q", *timesheetFormat)
+	}
+	if *timesheetDays <= 0 {
+		return errors.Errorf("-days must be positive, not %d", *timesheetDays)
+	}
+	now := time.Now()
+	y, m, d := now.Date()
+	from := time.Date(y, m, d-*timesheetDays+1, 0, 0, 0, 0, now.Location())
+	if len(*timesheetFrom) > 0 {
+		var err error
+		if from, err = time.ParseInLocation("2006-01-02", *timesheetFrom, now.Location()); err != nil {
+			return errors.Wrapf(err, "while parsing -from")
+		}
+	}
+	to := from.AddDate(0, 0, *timesheetDays)
+
+	if err := db.load(); err != nil {
+		return err
+	}
+	ivs, err := exportIntervals("from", from.UTC().Format(stamp), "to", to.UTC().Format(stamp))
+	if err != nil {
+		return err
+	}
+	ts := timesheet{hours: make(map[string][]time.Duration)}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		ts.days = append(ts.days, day)
+	}
+	byDesc := descriptions()
+	for _, iv := range ivs {
+		project := unassigned
+		if tk, ok := iv.taskOf(byDesc); ok && len(tk.Project) > 0 {
+			project = tk.Project
+		}
+		if _, ok := ts.hours[project]; !ok {
+			ts.hours[project] = make([]time.Duration, len(ts.days))
+			ts.projects = append(ts.projects, project)
+		}
+		for i, day := range ts.days {
+			// Intervals still running count up to now.
+			end := day.AddDate(0, 0, 1)
+			if now.Before(end) {
+				end = now
+			}
+			ts.hours[project][i] += iv.within(day, end)
+		}
+	}
+	sort.Strings(ts.projects)
+
+	out, err := openOutput(*timesheetOut)
+	if err != nil {
+		return err
+	}
+	err = write(out, ts.rows())
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
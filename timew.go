@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var useTimew = flag.Bool("timew", true,
+	"Track time in timewarrior when starting and stopping tasks. Turn it off if taskwarrior's"+
+		" timewarrior hook does that already.")
+
+// runTimew runs the timew binary with the given arguments.
+func runTimew(args ...string) ([]byte, error) {
+	return runCmd(exec.Command("timew", args...))
+}
+
+// interval is a stretch of time tracked in timewarrior, as it exports them.
+type interval struct {
+	Start string   `json:"start"`
+	End   string   `json:"end,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+func (iv interval) hasTag(tag string) bool {
+	for _, t := range iv.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// activeInterval returns what timewarrior is tracking right now, if anything.
+func activeInterval() (interval, bool) {
+	var iv interval
+	if !*useTimew {
+		return iv, false
+	}
+	out, err := runTimew("get", "dom.active.json")
+	if err != nil {
+		// Nothing is being tracked, or there's no timewarrior.
+		return iv, false
+	}
+	if err := json.Unmarshal(out, &iv); err != nil {
+		lg.Errorf("While parsing the active interval %q: %v", out, err)
+		return iv, false
+	}
+	return iv, true
+}
+
+// timewTags are the tags time on the task is tracked under. The uuid finds
+// the time again, and the rest are what the taskwarrior hook would use.
+func (tk task) timewTags() []string {
+	tags := []string{tk.Uuid, tk.Description}
+	if len(tk.Project) > 0 {
+		tags = append(tags, tk.Project)
+	}
+	for _, t := range tk.Tags {
+		if isNormalTag(t) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// timew runs timewarrior for a start or stop, unless it's turned off. It
+// tells of any failure in the notice, since the task change went through.
+func timew(args ...string) {
+	if !*useTimew || *dryRun {
+		return
+	}
+	if _, err := runTimew(args...); err != nil {
+		lg.Errorf("While running timew %q: %v", args, err)
+		notice = fmt.Sprintf("timew %s failed: %v", args[0], err)
+	}
+}
+
+// startTask starts the task, and tracks time on it. Only one task is
+// tracked at a time, so any other started task is stopped.
+func (t task) startTask() int {
+	if len(t.Start) > 0 {
+		notice = "Already started."
+		return 0
+	}
+	if t.Status != "pending" {
+		notice = "Only pending tasks can be started."
+		return 0
+	}
+	var others []task
+	for _, o := range db.tasks {
+		if len(o.Start) > 0 && o.Status == "pending" && o.Uuid != t.Uuid {
+			stopped := o.clone()
+			stopped.Start = ""
+			others = append(others, stopped)
+		}
+	}
+	if err := importBatch(others); err != nil {
+		return 0
+	}
+	t.Start = time.Now().UTC().Format(stamp)
+	t.doImport()
+	// Starting an interval in timewarrior ends the one before.
+	timew(append([]string{"start"}, t.timewTags()...)...)
+	forgetTracked()
+	return 0
+}
+
+// stopTask stops the task, and the time tracked on it.
+func (t task) stopTask() int {
+	if len(t.Start) == 0 {
+		notice = "Not started."
+		return 0
+	}
+	t.Start = ""
+	t.doImport()
+	// Leave alone whatever else is being tracked.
+	if iv, ok := activeInterval(); ok && iv.hasTag(t.Uuid) {
+		timew("stop")
+	}
+	forgetTracked()
+	return 0
+}
+
+// exportIntervals returns the intervals tracked in timewarrior under all of
+// the tags.
+func exportIntervals(tags ...string) ([]interval, error) {
+	out, err := runTimew(append([]string{"export"}, tags...)...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while exporting intervals for %q", tags)
+	}
+	var ivs []interval
+	if err := json.Unmarshal(out, &ivs); err != nil {
+		return nil, errors.Wrapf(err, "while parsing intervals for %q", tags)
+	}
+	return ivs, nil
+}
+
+// within is how long the interval ran between from and to, counting a
+// running interval as ending at to.
+func (iv interval) within(from, to time.Time) time.Duration {
+	start, err := time.Parse(stamp, iv.Start)
+	if err != nil {
+		return 0
+	}
+	end := to
+	if len(iv.End) > 0 {
+		if end, err = time.Parse(stamp, iv.End); err != nil {
+			return 0
+		}
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// taskOf returns the task the interval was tracked on, by its uuid tag or,
+// failing that, by a tag matching a description in byDesc.
+func (iv interval) taskOf(byDesc map[string]task) (task, bool) {
+	if t, ok := iv.taskByUuid(); ok {
+		return t, true
+	}
+	for _, tag := range iv.Tags {
+		if t, ok := byDesc[tag]; ok {
+			return t, true
+		}
+	}
+	return task{}, false
+}
+
+// taskByUuid returns the task the interval was tracked on, by its uuid tag.
+func (iv interval) taskByUuid() (task, bool) {
+	for _, tag := range iv.Tags {
+		if t, ok := db.get(tag); ok {
+			return t, true
+		}
+	}
+	return task{}, false
+}
+
+// descriptions indexes the stored tasks by description, to find the tasks
+// which taskwarrior's timewarrior hook tracked time on.
+func descriptions() map[string]task {
+	byDesc := make(map[string]task, len(db.tasks))
+	for _, t := range db.tasks {
+		byDesc[t.Description] = *t
+	}
+	return byDesc
+}
+
+// tracked is the time tracked on a task, as of when it was looked up.
+type tracked struct {
+	total   time.Duration
+	found   bool
+	running bool // Whether an interval was still open, and counts on from at.
+	at      time.Time
+}
+
+// trackedCache holds the time tracked on each task looked up this session, by
+// uuid. Only starting and stopping tasks here change it; time tracked outside
+// of taskreview shows up in the next session.
+var trackedCache = make(map[string]tracked)
+
+// activeCache holds what timewarrior was tracking when last asked, for the
+// screens to show without running timew on each render.
+var activeCache struct {
+	iv          interval
+	ok, checked bool
+}
+
+// cachedInterval is activeInterval, as of the last start or stop.
+func cachedInterval() (interval, bool) {
+	if !activeCache.checked {
+		activeCache.iv, activeCache.ok = activeInterval()
+		activeCache.checked = true
+	}
+	return activeCache.iv, activeCache.ok
+}
+
+// forgetTracked drops the cached tracked times, and what's being tracked.
+// Starting a task stops others, so it doesn't stop at one task.
+func forgetTracked() {
+	trackedCache = make(map[string]tracked)
+	activeCache.checked = false
+}
+
+// trackedTime returns the total time tracked on the task in timewarrior. Time
+// tracked by taskreview is found by the uuid; failing that, by the
+// description, which is what taskwarrior's timewarrior hook tags it with.
+func (tk task) trackedTime() (time.Duration, bool) {
+	if !*useTimew {
+		return 0, false
+	}
+	tr, ok := trackedCache[tk.Uuid]
+	if !ok {
+		tr = lookupTracked(tk)
+		trackedCache[tk.Uuid] = tr
+	}
+	if tr.running {
+		return tr.total + time.Since(tr.at), tr.found
+	}
+	return tr.total, tr.found
+}
+
+func lookupTracked(tk task) tracked {
+	tr := tracked{at: time.Now().UTC()}
+	var ivs []interval
+	for _, tag := range []string{tk.Uuid, tk.Description} {
+		if len(tag) == 0 {
+			continue
+		}
+		var err error
+		if ivs, err = exportIntervals(tag); err != nil {
+			lg.Debugf("%v", err)
+			return tr
+		}
+		if len(ivs) > 0 {
+			break
+		}
+	}
+	for _, iv := range ivs {
+		tr.total += iv.within(time.Time{}, tr.at)
+		if len(iv.End) == 0 {
+			tr.running = true
+		}
+	}
+	tr.found = len(ivs) > 0
+	return tr
+}
+
+// trackedPrefix marks annotations which record the time spent on a task.
+const trackedPrefix = "tracked: "
+
+// stopTracking clears the start of a task about to be completed or deleted,
+// and notes the time spent on it. It returns whether timewarrior is tracking
+// the task, so the caller stops it once the task is imported.
+func (t *task) stopTracking() bool {
+	since, ok := parseStamp(t.Start)
+	if !ok {
+		return false
+	}
+	t.Start = ""
+	iv, active := activeInterval()
+	active = active && iv.hasTag(t.Uuid)
+	spent, ok := t.trackedTime()
+	// The caller is about to stop its interval.
+	forgetTracked()
+	if !ok {
+		spent = time.Since(since)
+	}
+	t.annotate(trackedPrefix + strings.TrimSpace(age(spent)))
+	return active
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// triageRule sets fields on the tasks matching its expression. The
+// expression is a space separated list of terms, all of which must match:
+//
+//	color:red color:none   the task's color, or lack of one
+//	user:none              the task has no assignee
+//	project:ops            the project, or any of its subprojects
+//	+tag -tag              has, or doesn't have, the tag; +@alice for users
+//	age>14d                created longer ago than the duration
+//	word                   the description contains the word
+type triageRule struct {
+	Name  string `json:"name"`
+	Match string `json:"match"`
+	Set   struct {
+		Color    string `json:"color,omitempty"`
+		Tag      string `json:"tag,omitempty"`
+		Project  string `json:"project,omitempty"`
+		Assignee string `json:"assignee,omitempty"`
+	} `json:"set"`
+}
+
+// triageRules returns the configured rules, defaulting to giving tasks
+// without a color the fix state.
+func triageRules() []triageRule {
+	if len(cfg.Triage) > 0 {
+		return cfg.Triage
+	}
+	r := triageRule{Name: "fix", Match: "color:none"}
+	r.Set.Color = cfg.FixState
+	return []triageRule{r}
+}
+
+func (tk task) hasTag(tag string) bool {
+	for _, t := range tk.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (r triageRule) matches(tk task, now time.Time) bool {
+	for _, term := range strings.Fields(r.Match) {
+		var ok bool
+		switch {
+		case term == "color:none":
+			ok = len(tk.colorTag()) == 0
+		case strings.HasPrefix(term, "color:"):
+			ok = tk.colorTag() == term[len("color:"):]
+		case term == "user:none":
+			ok = len(tk.userTag()) == 0
+		case strings.HasPrefix(term, "project:"):
+			p := term[len("project:"):]
+			ok = tk.Project == p || strings.HasPrefix(tk.Project, p+".")
+		case strings.HasPrefix(term, "+"):
+			ok = tk.hasTag(term[1:])
+		case strings.HasPrefix(term, "-"):
+			ok = !tk.hasTag(term[1:])
+		case strings.HasPrefix(term, "age>"):
+			dur, err := parseDuration(term[len("age>"):])
+			if err != nil {
+				lg.Errorf("Invalid age in triage rule %q: %v", r.Name, err)
+				return false
+			}
+			created, err := time.Parse(stamp, tk.Created)
+			ok = err == nil && now.Sub(created) > dur
+		default:
+			ok = strings.Contains(strings.ToLower(tk.Description), strings.ToLower(term))
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the task with the rule's fields set.
+func (r triageRule) apply(tk task) task {
+	tags := make([]string, 0, len(tk.Tags)+2)
+	for _, t := range tk.Tags {
+		if len(r.Set.Color) > 0 && isStateTag(t) {
+			continue
+		}
+		if len(r.Set.Assignee) > 0 && strings.HasPrefix(t, "@") {
+			continue
+		}
+		tags = append(tags, t)
+	}
+	if len(r.Set.Color) > 0 {
+		tags = append(tags, r.Set.Color)
+	}
+	if len(r.Set.Assignee) > 0 {
+		tags = append(tags, "@"+strings.TrimPrefix(r.Set.Assignee, "@"))
+	}
+	if len(r.Set.Tag) > 0 && !tk.hasTag(r.Set.Tag) {
+		tags = append(tags, r.Set.Tag)
+	}
+	tk.Tags = tags
+	if len(r.Set.Project) > 0 {
+		tk.Project = r.Set.Project
+	}
+	return tk
+}
+
+// describe summarizes what the rule would change.
+func (r triageRule) describe() string {
+	var parts []string
+	if len(r.Set.Color) > 0 {
+		parts = append(parts, "color:"+r.Set.Color)
+	}
+	if len(r.Set.Assignee) > 0 {
+		parts = append(parts, "@"+strings.TrimPrefix(r.Set.Assignee, "@"))
+	}
+	if len(r.Set.Project) > 0 {
+		parts = append(parts, "project:"+r.Set.Project)
+	}
+	if len(r.Set.Tag) > 0 {
+		parts = append(parts, "+"+r.Set.Tag)
+	}
+	return strings.Join(parts, " ")
+}
+
+// triage runs the triage rules over the tasks, previews the changes, and
+// applies them once confirmed. The first matching rule wins. Changes can be
+// applied all at once, or picked per task; stopping early applies the ones
+// picked so far. Changes are imported as a batch.
+func triage(tasks []task) {
+	rules := triageRules()
+	now := time.Now().UTC()
+	var idx []int
+	var matched []triageRule
+	for i, tk := range tasks {
+		for _, r := range rules {
+			if r.matches(tk, now) {
+				idx = append(idx, i)
+				matched = append(matched, r)
+				break
+			}
+		}
+	}
+	if len(idx) == 0 {
+		return
+	}
+
+	clear()
+	boldRed.Printf("%d tasks to triage:\n\n", len(idx))
+	for j, i := range idx {
+		printSummary(tasks[i], i, len(tasks))
+		fmt.Printf("        %s: %s\n", matched[j].Name, matched[j].describe())
+	}
+	fmt.Printf("\nEnter to apply all, p to pick per task, any other key to abort.\n")
+	var pick bool
+	switch readKey() {
+	case 10: // Enter
+	case 'p':
+		pick = true
+	default:
+		return
+	}
+
+	var updates []task
+	var updated []int
+PICK:
+	for j, i := range idx {
+		r := matched[j]
+		if pick {
+			fmt.Println()
+			printSummary(tasks[i], i, len(tasks))
+			if len(r.Set.Color) > 0 {
+				// Allow picking a different color than the rule's.
+				ch := showAndGetResponse("Color (Enter for "+r.Set.Color+", Esc to stop)", "color")
+				if a, ok := short.MapsTo(ch, "color"); ok {
+					r.Set.Color = a
+				} else if ch == 27 {
+					break PICK
+				} else if ch != 10 {
+					continue
+				}
+			} else {
+				fmt.Printf("Apply %s? [y/N, Esc to stop] ", r.describe())
+				ch := readKey()
+				if ch == 27 {
+					break PICK
+				} else if ch != 'y' && ch != 'Y' {
+					continue
+				}
+			}
+		}
+		tk := r.apply(tasks[i])
+		if !withinLimit(tk) {
+			continue
+		}
+		fmt.Printf("\nTriaging task: %v -> %v\n", tk.Description, r.describe())
+		updates = append(updates, tk)
+		updated = append(updated, i)
+	}
+	if err := importBatch(updates); err != nil {
+		return
+	}
+	for j, i := range updated {
+		tasks[i] = updates[j]
+	}
+}
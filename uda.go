@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+var setup = flag.Bool("setup", false,
+	"Print the taskrc UDA definitions taskreview needs, and exit.")
+
+// udas lists the user defined attributes taskreview stores on tasks. They
+// must be declared in taskrc, so that task sync doesn't strip them.
+var udas = []struct {
+	name, kind, label string
+}{
+	{"reviewed_at", "string", "Reviewed At"},
+	{"reviewed_by", "string", "Reviewed By"},
+	{"pomodoros", "numeric", "Pomodoros"},
+	{"client", "string", "Client"},
+	{"estimate", "duration", "Estimate"},
+}
+
+func printSetup() {
+	fmt.Println("# Append these to your ~/.taskrc, on every machine you sync with.")
+	for _, u := range udas {
+		fmt.Printf("uda.%s.type=%s\n", u.name, u.kind)
+		fmt.Printf("uda.%s.label=%s\n", u.name, u.label)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var uuidsPath = flag.String("uuids", "",
+	"File listing the uuids of the tasks to review, one per line, or as a task export. - reads stdin.")
+
+// readUuids reads the uuids listed at path, or on stdin for -. The list is
+// either one uuid per line, with blank lines and # comments ignored, or a
+// JSON export of the tasks.
+func readUuids(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while opening uuids file")
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading uuids")
+	}
+
+	var uuids []string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var tasks []task
+		if err := json.Unmarshal(trimmed, &tasks); err != nil {
+			return nil, errors.Wrapf(err, "while parsing the task export")
+		}
+		for _, t := range tasks {
+			if len(t.Uuid) > 0 {
+				uuids = append(uuids, t.Uuid)
+			}
+		}
+		return uuids, nil
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.ToLower(strings.TrimSpace(line))
+		if len(line) == 0 {
+			continue
+		}
+		if !uuidPrefixExp.MatchString(line) {
+			return nil, errors.Errorf("%q isn't a uuid", line)
+		}
+		uuids = append(uuids, line)
+	}
+	return uuids, nil
+}
+
+// reopenTerminal has keys read from the terminal, once stdin was used up for
+// the list of uuids.
+func reopenTerminal() error {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return errors.Wrapf(err, "while opening the terminal, after reading stdin")
+	}
+	os.Stdin = tty
+	return nil
+}
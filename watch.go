@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var watchEvery = flag.Duration("watch", 0,
+	"Check this often, down to every 100ms, for tasks changed elsewhere, and refresh the list when they are. Zero turns it off.")
+
+// dataDir is where taskwarrior keeps its data, found on first use.
+var dataDir string
+
+func taskDataDir() string {
+	if len(dataDir) > 0 {
+		return dataDir
+	}
+	out, err := runTask("_get", "rc.data.location")
+	dir := strings.TrimSpace(string(out))
+	if err != nil || len(dir) == 0 {
+		dir = "~/.task"
+	}
+	if strings.HasPrefix(dir, "~/") {
+		dir = filepath.Join(os.Getenv("HOME"), dir[2:])
+	}
+	dataDir = dir
+	return dataDir
+}
+
+// dataStamp sums up the sizes and modification times of taskwarrior's data
+// files, which change whenever a task does. It's much cheaper than an export.
+func dataStamp() string {
+	files, err := ioutil.ReadDir(taskDataDir())
+	if err != nil {
+		lg.Errorf("While watching %q: %v", taskDataDir(), err)
+		return ""
+	}
+	var stamp []string
+	for _, f := range files {
+		if f.Mode().IsRegular() {
+			stamp = append(stamp, fmt.Sprintf("%s %d %d", f.Name(), f.Size(), f.ModTime().UnixNano()))
+		}
+	}
+	return strings.Join(stamp, ",")
+}
+
+// watchKey reads a key like contextKey does. While watching, it returns
+// false instead, once the tasks change.
+func watchKey(group string) (rune, bool) {
+	if *watchEvery <= 0 {
+		return contextKey(group), true
+	}
+	stamp := dataStamp()
+	for {
+		r, ok := readKeyTimeout(*watchEvery)
+		if !ok {
+			if dataStamp() != stamp {
+				lg.Infof("Tasks changed elsewhere. Refreshing.")
+				return 0, false
+			}
+			continue
+		}
+		if !isHelpKey(r, group) {
+			return r, true
+		}
+		showOverlay(group)
+	}
+}
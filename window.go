@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// windowPrefix marks the completed window in the filter, as in "_end:2w".
+// It's taken out before the filter is passed on to taskwarrior.
+const windowPrefix = "_end:"
+
+// parseWindow parses a completed window, as in "2w", "30d", "3m" or "all".
+// A month counts as 30 days. All comes back as zero.
+func parseWindow(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "all" {
+		return 0, nil
+	}
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid window %q", s)
+	}
+	unit := map[byte]time.Duration{
+		'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour, 'm': 30 * 24 * time.Hour,
+	}
+	u, ok := unit[s[len(s)-1]]
+	if !ok {
+		return 0, fmt.Errorf("window %q should end in d, w or m", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid window %q", s)
+	}
+	return time.Duration(n) * u, nil
+}
+
+// splitWindow takes the completed window out of the filter. Filters saved by
+// older versions have a bare "_end" per week instead, which still work.
+func splitWindow(filter string) (rest []string, window string) {
+	var weeks int
+	for _, arg := range strings.Split(filter, " ") {
+		switch {
+		case len(arg) == 0:
+		case arg == "_end":
+			weeks++
+		case strings.HasPrefix(arg, windowPrefix):
+			window = arg[len(windowPrefix):]
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if len(window) == 0 && weeks > 0 {
+		window = fmt.Sprintf("%dw", weeks)
+	}
+	return rest, window
+}
+
+// withWindow replaces the completed window in the filter. An empty window
+// goes back to pending tasks.
+func withWindow(filter, window string) string {
+	rest, _ := splitWindow(filter)
+	if len(window) > 0 {
+		rest = append(rest, windowPrefix+window)
+	}
+	return strings.Join(rest, " ")
+}
+
+// askWindow prompts for the completed window, until it gets a valid one.
+// Empty input keeps the current window, and "-" goes back to pending tasks.
+func askWindow(filter string) string {
+	_, window := splitWindow(filter)
+	if len(window) == 0 {
+		window = "pending"
+	}
+	fmt.Println()
+	for {
+		in := readLine(fmt.Sprintf("Completed within 2w, 30d, 3m or all; - for pending [%s]: ", window))
+		switch {
+		case len(in) == 0:
+			return filter
+		case in == "-":
+			return withWindow(filter, "")
+		}
+		if _, err := parseWindow(in); err != nil {
+			boldRed.Printf("%v\n", err)
+			continue
+		}
+		return withWindow(filter, in)
+	}
+}
+
+// completedGroup is the header a completed task is listed under, when sorted
+// by date: its day over the last week, and its week before that.
+func completedGroup(tk task, now time.Time) string {
+	end, ok := tk.ended()
+	if !ok || tk.Status != "completed" {
+		return ""
+	}
+	end = end.Local()
+	day := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.Local)
+	if now.Sub(day) < 7*24*time.Hour {
+		return day.Format("Mon Jan 2")
+	}
+	// Weeks start on Monday.
+	monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	return "Week of " + monday.Format("Jan 2")
+}
+
+// reviewOldCompleted marks completed tasks matching the filter, which ended
+// longer ago than asked for and haven't been reviewed, as reviewed. That
+// way old history doesn't pile up as unreviewed, after time away.
+func reviewOldCompleted(filter string) {
+	fmt.Println()
+	in := readLine("Mark completed tasks older than (for e.g. 30d, 2w or 3m) as reviewed: ")
+	if len(in) == 0 {
+		return
+	}
+	older, err := parseWindow(in)
+	if err != nil || older == 0 {
+		boldRed.Printf("Invalid age %q. Press any key to continue.\n", in)
+		readKey()
+		return
+	}
+	tasks, err := getTasks(withWindow(filter, "all"))
+	if err != nil {
+		lg.Fatalf("While getting completed tasks for filter %q: %v", filter, err)
+	}
+	now := time.Now().UTC()
+	var old []task
+	for _, tk := range tasks {
+		if end, ok := tk.ended(); ok && now.Sub(end) > older && !tk.isReviewed() {
+			old = append(old, tk)
+		}
+	}
+	if len(old) == 0 {
+		fmt.Printf("No unreviewed tasks completed over %s ago. Press any key to continue.\n", in)
+		readKey()
+		return
+	}
+	fmt.Printf("Mark %d tasks completed over %s ago as reviewed? [y/N] ", len(old), in)
+	if r := readKey(); r != 'y' && r != 'Y' {
+		fmt.Println()
+		return
+	}
+	fmt.Println()
+	for i := range old {
+		old[i].markReviewed()
+	}
+	importBatch(old)
+}
+
+// printTrend shows how many tasks were completed against how many were
+// created, over the completed window of the filter. It's a quick check on
+// whether we're keeping up.
+func printTrend(filter string, completed []task) {
+	rest, window := splitWindow(filter)
+	within, err := parseWindow(window)
+	if len(window) == 0 || err != nil || within == 0 {
+		return
+	}
+	n, ok := createdSince(rest, time.Now().UTC().Add(-within))
+	if !ok {
+		return
+	}
+	c := boldGreen
+	if n > len(completed) {
+		c = boldRed
+	}
+	fmt.Println()
+	c.Printf("> Completed %d / created %d in the last %s.\n", len(completed), n, window)
+}
+
+// createdSince counts the tasks matching the filter args which were created
+// since the given time, leaving out deleted ones. The store answers it, when
+// it can.
+func createdSince(args []string, since time.Time) (int, bool) {
+	var n int
+	if tasks, ok := db.match(args); ok {
+		for _, tk := range tasks {
+			if created, ok := tk.entered(); ok && created.After(since) {
+				n++
+			}
+		}
+		return n, true
+	}
+	out, err := runTask(append(args, "entry.after:"+since.Format(stamp), "export")...)
+	if err != nil {
+		lg.Errorf("While getting tasks created since %v: %v", since, err)
+		return 0, false
+	}
+	var created []task
+	if err := json.Unmarshal(out, &created); err != nil {
+		lg.Errorf("While parsing tasks created since %v: %v", since, err)
+		return 0, false
+	}
+	for _, tk := range created {
+		if tk.Status != "deleted" {
+			n++
+		}
+	}
+	return n, true
+}
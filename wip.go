@@ -0,0 +1,45 @@
+package main
+
+import "fmt"
+
+// withinLimit checks whether the task, as about to be imported, would take
+// its assignee over the work in progress limit of its state. If so, it
+// shows the tasks holding the slots and asks whether to go ahead anyway.
+func withinLimit(tk task) bool {
+	if len(tk.Completed) > 0 || tk.Status == "deleted" {
+		return true
+	}
+	i, ok := findState(tk.colorTag())
+	if !ok || cfg.States[i].Limit <= 0 {
+		return true
+	}
+	s, user := cfg.States[i], tk.userTag()
+	if len(user) == 0 {
+		return true
+	}
+
+	tasks, err := getTasks(fmt.Sprintf("+%s +%s", user, s.Name))
+	if err != nil {
+		lg.Fatalf("While getting %s tasks for %s: %v", s.Name, user, err)
+	}
+	var holders []task
+	for _, other := range tasks {
+		if other.Uuid != tk.Uuid {
+			holders = append(holders, other)
+		}
+	}
+	if len(holders) < s.Limit {
+		return true
+	}
+
+	fmt.Println()
+	boldRed.Printf("%s already has %d %s tasks, over the limit of %d:\n\n",
+		user, len(holders), stateBadge(s.Name), s.Limit)
+	for j, h := range holders {
+		printSummary(h, j, len(holders))
+	}
+	fmt.Printf("\nFinish one of these first? Press y to go ahead anyway. [y/N] ")
+	r := readKey()
+	fmt.Println()
+	return r == 'y' || r == 'Y'
+}